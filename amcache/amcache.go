@@ -0,0 +1,252 @@
+// Package amcache decodes program execution entries of Amcache.hve hives
+// opened with regffs.
+package amcache
+
+import (
+	"encoding/binary"
+	"errors"
+	"io/fs"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/forensicanalysis/regffs"
+)
+
+// Schema variants of Amcache.hve.
+const (
+	// SchemaFile is used by Windows 8 and Windows 10 up to 1607. Entries
+	// are stored under Root\File\{volume GUID}\{file reference} with values
+	// named by hexadecimal numbers.
+	SchemaFile = "File"
+	// SchemaInventory is used since Windows 10 1709. Entries are stored
+	// under Root\InventoryApplicationFile with named values.
+	SchemaInventory = "InventoryApplicationFile"
+)
+
+const (
+	fileKey      = "Root/File"
+	inventoryKey = "Root/InventoryApplicationFile"
+)
+
+// linkDateLayout is the layout of the LinkDate value of the inventory
+// schema.
+const linkDateLayout = "01/02/2006 15:04:05"
+
+// Entry is a file entry of the Amcache.
+type Entry struct {
+	Path string `json:"path"`
+	// SHA1 of the first 31.4 MB of the file, without the leading zeros of
+	// the stored value.
+	SHA1        string    `json:"sha1,omitempty"`
+	Size        uint64    `json:"size,omitempty"`
+	LinkDate    time.Time `json:"link_date"`
+	Publisher   string    `json:"publisher,omitempty"`
+	ProductName string    `json:"product_name,omitempty"`
+	Version     string    `json:"version,omitempty"`
+	ProgramID   string    `json:"program_id,omitempty"`
+	// VolumeGUID, MFTEntry and MFTSequence are only available in the file
+	// schema.
+	VolumeGUID  string `json:"volume_guid,omitempty"`
+	MFTEntry    uint64 `json:"mft_entry,omitempty"`
+	MFTSequence uint16 `json:"mft_sequence,omitempty"`
+	// LastModified and Created are only available in the file schema.
+	LastModified time.Time `json:"last_modified"`
+	Created      time.Time `json:"created"`
+	// KeyModTime is the last written time of the entry key, which is
+	// usually the time of the first execution.
+	KeyModTime time.Time `json:"key_mtime"`
+}
+
+// Schema detects the schema variant of an Amcache.hve hive.
+func Schema(r *regffs.Regffs) (string, error) {
+	switch {
+	case r.Exists(inventoryKey):
+		return SchemaInventory, nil
+	case r.Exists(fileKey):
+		return SchemaFile, nil
+	}
+	return "", errors.New("no Amcache file entries found")
+}
+
+// Entries detects the schema variant and returns the file entries.
+func Entries(r *regffs.Regffs) (string, []*Entry, error) {
+	schema, err := Schema(r)
+	if err != nil {
+		return "", nil, err
+	}
+	var entries []*Entry
+	if schema == SchemaInventory {
+		entries, err = inventoryEntries(r)
+	} else {
+		entries, err = fileEntries(r)
+	}
+	return schema, entries, err
+}
+
+func inventoryEntries(r *regffs.Regffs) ([]*Entry, error) {
+	keys, err := fs.ReadDir(r, inventoryKey)
+	if err != nil {
+		return nil, err
+	}
+	var entries []*Entry
+	for _, key := range keys {
+		if !key.IsDir() {
+			continue
+		}
+		values, err := r.Values(path.Join(inventoryKey, key.Name()))
+		if err != nil {
+			return nil, err
+		}
+		entry := parseInventoryEntry(values)
+		entry.KeyModTime = modTime(key)
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func parseInventoryEntry(values map[string]*regffs.Value) *Entry {
+	entry := &Entry{
+		Path:        stringValue(values, "lowercaselongpath"),
+		SHA1:        sha1Value(values, "fileid"),
+		Size:        sizeValue(values, "size"),
+		Publisher:   stringValue(values, "publisher"),
+		ProductName: stringValue(values, "productname"),
+		Version:     stringValue(values, "version"),
+		ProgramID:   stringValue(values, "programid"),
+	}
+	if linkDate, err := time.Parse(linkDateLayout, stringValue(values, "linkdate")); err == nil {
+		entry.LinkDate = linkDate
+	}
+	return entry
+}
+
+// Values of the file schema.
+const (
+	valueProductName  = "0"
+	valueCompanyName  = "1"
+	valueFileVersion  = "5"
+	valueFileSize     = "6"
+	valueLinkDate     = "f"
+	valueLastModified = "11"
+	valueCreated      = "12"
+	valuePath         = "15"
+	valueProgramID    = "100"
+	valueSHA1         = "101"
+)
+
+func fileEntries(r *regffs.Regffs) ([]*Entry, error) {
+	volumes, err := fs.ReadDir(r, fileKey)
+	if err != nil {
+		return nil, err
+	}
+	var entries []*Entry
+	for _, volume := range volumes {
+		if !volume.IsDir() {
+			continue
+		}
+		volumeKey := path.Join(fileKey, volume.Name())
+		files, err := fs.ReadDir(r, volumeKey)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			if !file.IsDir() {
+				continue
+			}
+			values, err := r.Values(path.Join(volumeKey, file.Name()))
+			if err != nil {
+				return nil, err
+			}
+			entry := parseFileEntry(values)
+			entry.VolumeGUID = volume.Name()
+			entry.MFTEntry, entry.MFTSequence = parseFileReference(file.Name())
+			entry.KeyModTime = modTime(file)
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+func parseFileEntry(values map[string]*regffs.Value) *Entry {
+	entry := &Entry{
+		Path:         stringValue(values, valuePath),
+		SHA1:         sha1Value(values, valueSHA1),
+		Size:         sizeValue(values, valueFileSize),
+		Publisher:    stringValue(values, valueCompanyName),
+		ProductName:  stringValue(values, valueProductName),
+		Version:      stringValue(values, valueFileVersion),
+		ProgramID:    stringValue(values, valueProgramID),
+		LastModified: filetimeValue(values, valueLastModified),
+		Created:      filetimeValue(values, valueCreated),
+	}
+	if v, ok := values[valueLinkDate]; ok {
+		if linkDate, err := v.Uint(); err == nil && linkDate != 0 {
+			entry.LinkDate = time.Unix(int64(linkDate), 0).UTC()
+		}
+	}
+	return entry
+}
+
+// parseFileReference decodes a hexadecimal NTFS file reference, the MFT
+// entry in the lower 48 bits and the sequence number in the upper 16 bits.
+func parseFileReference(name string) (uint64, uint16) {
+	reference, err := strconv.ParseUint(name, 16, 64)
+	if err != nil {
+		return 0, 0
+	}
+	return reference & 0xffffffffffff, uint16(reference >> 48)
+}
+
+func modTime(entry fs.DirEntry) time.Time {
+	info, err := entry.Info()
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+func stringValue(values map[string]*regffs.Value, name string) string {
+	if v, ok := values[name]; ok {
+		return v.String()
+	}
+	return ""
+}
+
+// sha1Value returns the SHA-1 of a FileId value, which is prefixed with
+// four zeros.
+func sha1Value(values map[string]*regffs.Value, name string) string {
+	s := strings.ToLower(stringValue(values, name))
+	if len(s) == 44 && strings.HasPrefix(s, "0000") {
+		return s[4:]
+	}
+	return s
+}
+
+// sizeValue decodes sizes stored as integer or, in some builds, as
+// string.
+func sizeValue(values map[string]*regffs.Value, name string) uint64 {
+	v, ok := values[name]
+	if !ok {
+		return 0
+	}
+	if size, err := v.Uint(); err == nil {
+		return size
+	}
+	s := v.String()
+	if strings.HasPrefix(s, "0x") {
+		size, _ := strconv.ParseUint(s[2:], 16, 64)
+		return size
+	}
+	size, _ := strconv.ParseUint(s, 10, 64)
+	return size
+}
+
+func filetimeValue(values map[string]*regffs.Value, name string) time.Time {
+	v, ok := values[name]
+	if !ok || len(v.Data) != 8 {
+		return time.Time{}
+	}
+	return regffs.FiletimeToTime(binary.LittleEndian.Uint64(v.Data))
+}
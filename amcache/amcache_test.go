@@ -0,0 +1,65 @@
+package amcache
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/forensicanalysis/regffs"
+)
+
+func TestParseInventoryEntry(t *testing.T) {
+	got := parseInventoryEntry(map[string]*regffs.Value{
+		"lowercaselongpath": regffs.StringValue(`c:\users\joe\downloads\putty.exe`),
+		"fileid":            regffs.StringValue("0000a3f8f8e0e9ebf4a1b2f1c1d2e3f4a5b6c7d8e9f0"),
+		"size":              regffs.QwordValue(1115536),
+		"linkdate":          regffs.StringValue("07/10/2017 18:17:07"),
+		"publisher":         regffs.StringValue("simon tatham"),
+		"productname":       regffs.StringValue("putty suite"),
+		"version":           regffs.StringValue("release 0.70"),
+		"programid":         regffs.StringValue("0006a4c1d2e3f4a5b6c7d8e9f0a1b2c3d4e50000ffff"),
+	})
+	want := &Entry{
+		Path:        `c:\users\joe\downloads\putty.exe`,
+		SHA1:        "a3f8f8e0e9ebf4a1b2f1c1d2e3f4a5b6c7d8e9f0",
+		Size:        1115536,
+		LinkDate:    time.Date(2017, 7, 10, 18, 17, 7, 0, time.UTC),
+		Publisher:   "simon tatham",
+		ProductName: "putty suite",
+		Version:     "release 0.70",
+		ProgramID:   "0006a4c1d2e3f4a5b6c7d8e9f0a1b2c3d4e50000ffff",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseInventoryEntry() = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseFileEntry(t *testing.T) {
+	modified := time.Date(2015, 3, 2, 10, 11, 12, 0, time.UTC)
+	got := parseFileEntry(map[string]*regffs.Value{
+		valuePath:         regffs.StringValue(`C:\Windows\System32\calc.exe`),
+		valueSHA1:         regffs.StringValue("00005a6c9d2e4f8a1b3c5d7e9f0a2b4c6d8e0f1a3b5c"),
+		valueFileSize:     regffs.DwordValue(918528),
+		valueLinkDate:     regffs.DwordValue(1290246291),
+		valueCompanyName:  regffs.StringValue("Microsoft Corporation"),
+		valueLastModified: regffs.QwordValue(uint64(modified.Unix())*1e7 + 116444736000000000),
+	})
+	want := &Entry{
+		Path:         `C:\Windows\System32\calc.exe`,
+		SHA1:         "5a6c9d2e4f8a1b3c5d7e9f0a2b4c6d8e0f1a3b5c",
+		Size:         918528,
+		LinkDate:     time.Date(2010, 11, 20, 9, 44, 51, 0, time.UTC),
+		Publisher:    "Microsoft Corporation",
+		LastModified: modified,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseFileEntry() = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseFileReference(t *testing.T) {
+	entry, sequence := parseFileReference("1000000002c1b")
+	if entry != 0x2c1b || sequence != 1 {
+		t.Errorf("parseFileReference() = %d, %d, want %d, 1", entry, sequence, 0x2c1b)
+	}
+}
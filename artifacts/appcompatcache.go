@@ -0,0 +1,297 @@
+package artifacts
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"time"
+	"unicode/utf16"
+
+	"github.com/forensicanalysis/regffs"
+)
+
+// AppCompatCache formats.
+const (
+	FormatWindowsXP    = "Windows XP"
+	FormatWindows2003  = "Windows 2003"
+	FormatWindowsVista = "Windows Vista"
+	FormatWindows7     = "Windows 7"
+	FormatWindows8     = "Windows 8"
+	FormatWindows81    = "Windows 8.1"
+	FormatWindows10    = "Windows 10"
+)
+
+// insertFlagExecuted is set in the insert flags of executed entries.
+const insertFlagExecuted = 0x00000002
+
+// AppCompatCacheEntry is an entry of the AppCompatCache (ShimCache).
+type AppCompatCacheEntry struct {
+	// Position in the cache, 0 is the most recently inserted entry.
+	Position     int       `json:"position"`
+	Path         string    `json:"path"`
+	LastModified time.Time `json:"last_modified"`
+	// Size is only available on Windows XP and 2003.
+	Size uint64 `json:"size,omitempty"`
+	// LastUpdate is only available on Windows XP.
+	LastUpdate time.Time `json:"last_update,omitempty"`
+	// Executed is only available on Windows Vista, 7 and 8.
+	Executed *bool `json:"executed,omitempty"`
+}
+
+// AppCompatCache decodes the AppCompatCache of the current control set of
+// a SYSTEM hive. It returns the detected format and the entries.
+func AppCompatCache(r *regffs.Regffs) (string, []*AppCompatCacheEntry, error) {
+	controlSet, err := CurrentControlSet(r)
+	if err != nil {
+		return "", nil, err
+	}
+	for _, key := range []string{
+		"Control/Session Manager/AppCompatCache/AppCompatCache",
+		"Control/Session Manager/AppCompatibility/AppCompatCache",
+	} {
+		v, err := r.Value(path.Join(controlSet, key))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return "", nil, err
+		}
+		return DecodeAppCompatCache(v.Data)
+	}
+	return "", nil, fs.ErrNotExist
+}
+
+// DecodeAppCompatCache detects the format of an AppCompatCache value from
+// its signature and decodes its entries.
+func DecodeAppCompatCache(b []byte) (string, []*AppCompatCacheEntry, error) {
+	if len(b) < 8 {
+		return "", nil, errors.New("AppCompatCache too short")
+	}
+	switch signature := binary.LittleEndian.Uint32(b); {
+	case signature == 0xdeadbeef:
+		entries, err := decodeAppCompatCacheXP(b)
+		return FormatWindowsXP, entries, err
+	case signature == 0xbadc0ffe:
+		return decodeAppCompatCacheVista(b)
+	case signature == 0xbadc0fee:
+		entries, err := decodeAppCompatCache7(b)
+		return FormatWindows7, entries, err
+	case signature == 0x80 && len(b) >= 0x84 && string(b[0x80:0x84]) == "00ts":
+		entries, err := decodeAppCompatCache8(b[0x80:], false)
+		return FormatWindows8, entries, err
+	case signature == 0x80 && len(b) >= 0x84 && string(b[0x80:0x84]) == "10ts":
+		entries, err := decodeAppCompatCache8(b[0x80:], true)
+		return FormatWindows81, entries, err
+	case (signature == 0x30 || signature == 0x34) && len(b) >= int(signature)+4 && string(b[signature:signature+4]) == "10ts":
+		entries, err := decodeAppCompatCache10(b[signature:])
+		return FormatWindows10, entries, err
+	default:
+		return "", nil, fmt.Errorf("unknown AppCompatCache signature 0x%08x", signature)
+	}
+}
+
+func decodeAppCompatCacheXP(b []byte) ([]*AppCompatCacheEntry, error) {
+	const headerSize, entrySize = 0x190, 0x228
+	count := int(binary.LittleEndian.Uint32(b[4:]))
+	var entries []*AppCompatCacheEntry
+	for i := 0; i < count; i++ {
+		offset := headerSize + i*entrySize
+		if offset+entrySize > len(b) {
+			return entries, errors.New("AppCompatCache truncated")
+		}
+		e := b[offset : offset+entrySize]
+		entries = append(entries, &AppCompatCacheEntry{
+			Position:     i,
+			Path:         utf16z(e[:0x210]),
+			LastModified: regffs.FiletimeToTime(binary.LittleEndian.Uint64(e[0x210:])),
+			Size:         binary.LittleEndian.Uint64(e[0x218:]),
+			LastUpdate:   regffs.FiletimeToTime(binary.LittleEndian.Uint64(e[0x220:])),
+		})
+	}
+	return entries, nil
+}
+
+// decodeAppCompatCacheVista decodes the format shared by Windows 2003 and
+// Vista. Both are told apart by the last eight bytes of the entries: the
+// file size in 2003 and the insert and shim flags in Vista. Vista only uses
+// the low bits of the flags, so larger values are treated as file sizes.
+func decodeAppCompatCacheVista(b []byte) (string, []*AppCompatCacheEntry, error) {
+	const headerSize = 8
+	count := int(binary.LittleEndian.Uint32(b[4:]))
+	is64 := is64BitEntry(b[headerSize:])
+	entrySize, extra := 24, 16
+	if is64 {
+		entrySize, extra = 32, 24
+	}
+
+	var err error
+	if headerSize+count*entrySize > len(b) {
+		count = (len(b) - headerSize) / entrySize
+		err = errors.New("AppCompatCache truncated")
+	}
+
+	format := FormatWindowsVista
+	for i := 0; i < count; i++ {
+		e := b[headerSize+i*entrySize:]
+		if binary.LittleEndian.Uint32(e[extra:]) > 0xffff {
+			format = FormatWindows2003
+		}
+	}
+
+	var entries []*AppCompatCacheEntry
+	for i := 0; i < count; i++ {
+		e := b[headerSize+i*entrySize : headerSize+(i+1)*entrySize]
+		entry := &AppCompatCacheEntry{
+			Position:     i,
+			Path:         unicodeString(b, e, is64),
+			LastModified: regffs.FiletimeToTime(binary.LittleEndian.Uint64(e[extra-8:])),
+		}
+		if format == FormatWindows2003 {
+			entry.Size = binary.LittleEndian.Uint64(e[extra:])
+		} else {
+			executed := binary.LittleEndian.Uint32(e[extra:])&insertFlagExecuted != 0
+			entry.Executed = &executed
+		}
+		entries = append(entries, entry)
+	}
+	return format, entries, err
+}
+
+func decodeAppCompatCache7(b []byte) ([]*AppCompatCacheEntry, error) {
+	const headerSize = 0x80
+	if len(b) < headerSize {
+		return nil, errors.New("AppCompatCache truncated")
+	}
+	count := int(binary.LittleEndian.Uint32(b[4:]))
+	is64 := is64BitEntry(b[headerSize:])
+	entrySize, extra := 32, 16
+	if is64 {
+		entrySize, extra = 48, 24
+	}
+
+	var entries []*AppCompatCacheEntry
+	for i := 0; i < count; i++ {
+		offset := headerSize + i*entrySize
+		if offset+entrySize > len(b) {
+			return entries, errors.New("AppCompatCache truncated")
+		}
+		e := b[offset : offset+entrySize]
+		executed := binary.LittleEndian.Uint32(e[extra:])&insertFlagExecuted != 0
+		entries = append(entries, &AppCompatCacheEntry{
+			Position:     i,
+			Path:         unicodeString(b, e, is64),
+			LastModified: regffs.FiletimeToTime(binary.LittleEndian.Uint64(e[extra-8:])),
+			Executed:     &executed,
+		})
+	}
+	return entries, nil
+}
+
+func decodeAppCompatCache8(b []byte, win81 bool) ([]*AppCompatCacheEntry, error) {
+	var entries []*AppCompatCacheEntry
+	for offset := 0; offset+12 <= len(b); {
+		e := b[offset:]
+		if string(e[:4]) != "00ts" && string(e[:4]) != "10ts" {
+			break
+		}
+		size := int(binary.LittleEndian.Uint32(e[8:]))
+		if 12+size > len(e) {
+			return entries, errors.New("AppCompatCache truncated")
+		}
+		d := e[12 : 12+size]
+
+		p, rest, ok := sizedUTF16(d)
+		if ok && win81 {
+			_, rest, ok = sizedUTF16(rest)
+		}
+		if !ok || len(rest) < 16 {
+			return entries, errors.New("AppCompatCache entry truncated")
+		}
+		executed := binary.LittleEndian.Uint32(rest)&insertFlagExecuted != 0
+		entries = append(entries, &AppCompatCacheEntry{
+			Position:     len(entries),
+			Path:         p,
+			LastModified: regffs.FiletimeToTime(binary.LittleEndian.Uint64(rest[8:])),
+			Executed:     &executed,
+		})
+		offset += 12 + size
+	}
+	return entries, nil
+}
+
+func decodeAppCompatCache10(b []byte) ([]*AppCompatCacheEntry, error) {
+	var entries []*AppCompatCacheEntry
+	for offset := 0; offset+12 <= len(b); {
+		e := b[offset:]
+		if string(e[:4]) != "10ts" {
+			break
+		}
+		size := int(binary.LittleEndian.Uint32(e[8:]))
+		if 12+size > len(e) {
+			return entries, errors.New("AppCompatCache truncated")
+		}
+		d := e[12 : 12+size]
+
+		p, rest, ok := sizedUTF16(d)
+		if !ok || len(rest) < 8 {
+			return entries, errors.New("AppCompatCache entry truncated")
+		}
+		entries = append(entries, &AppCompatCacheEntry{
+			Position:     len(entries),
+			Path:         p,
+			LastModified: regffs.FiletimeToTime(binary.LittleEndian.Uint64(rest)),
+		})
+		offset += 12 + size
+	}
+	return entries, nil
+}
+
+// is64BitEntry detects 64-bit UNICODE_STRING structures of the first
+// entry, which have four bytes of padding after the length fields where
+// 32-bit structures store the path offset.
+func is64BitEntry(e []byte) bool {
+	return len(e) >= 8 && binary.LittleEndian.Uint32(e[4:]) == 0
+}
+
+// unicodeString decodes the path referenced by the UNICODE_STRING at the
+// start of entry e from the cache b.
+func unicodeString(b, e []byte, is64 bool) string {
+	length := int(binary.LittleEndian.Uint16(e))
+	var offset int
+	if is64 {
+		offset = int(binary.LittleEndian.Uint64(e[8:]))
+	} else {
+		offset = int(binary.LittleEndian.Uint32(e[4:]))
+	}
+	if offset < 0 || offset+length > len(b) {
+		return ""
+	}
+	return utf16z(b[offset : offset+length])
+}
+
+// sizedUTF16 decodes an UTF-16 string prefixed by its size in bytes.
+func sizedUTF16(b []byte) (string, []byte, bool) {
+	if len(b) < 2 {
+		return "", nil, false
+	}
+	size := int(binary.LittleEndian.Uint16(b))
+	if 2+size > len(b) {
+		return "", nil, false
+	}
+	return utf16z(b[2 : 2+size]), b[2+size:], true
+}
+
+// utf16z decodes an UTF-16 string up to the first end-of-string character.
+func utf16z(b []byte) string {
+	u16s := make([]uint16, 0, len(b)/2)
+	for i := 0; i+1 < len(b); i += 2 {
+		c := binary.LittleEndian.Uint16(b[i:])
+		if c == 0 {
+			break
+		}
+		u16s = append(u16s, c)
+	}
+	return string(utf16.Decode(u16s))
+}
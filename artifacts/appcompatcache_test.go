@@ -0,0 +1,82 @@
+package artifacts
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/forensicanalysis/regffs"
+)
+
+func filetime(t time.Time) uint64 {
+	return uint64(t.UnixNano()/100) + 116444736000000000
+}
+
+func TestDecodeAppCompatCache(t *testing.T) {
+	modified := time.Date(2020, 5, 17, 10, 11, 12, 0, time.UTC)
+	const path = `C:\Windows\System32\cmd.exe`
+
+	// Windows XP, fixed size entries after a 0x190 byte header
+	xp := make([]byte, 0x190+0x228)
+	binary.LittleEndian.PutUint32(xp, 0xdeadbeef)
+	binary.LittleEndian.PutUint32(xp[4:], 1)
+	copy(xp[0x190:], regffs.EncodeUTF16(path))
+	binary.LittleEndian.PutUint64(xp[0x190+0x210:], filetime(modified))
+	binary.LittleEndian.PutUint64(xp[0x190+0x218:], 1234)
+
+	// Windows 7 64-bit, 48 byte entries with the path stored after them
+	win7 := make([]byte, 0x80+48)
+	binary.LittleEndian.PutUint32(win7, 0xbadc0fee)
+	binary.LittleEndian.PutUint32(win7[4:], 1)
+	binary.LittleEndian.PutUint16(win7[0x80:], uint16(2*len(path)))
+	binary.LittleEndian.PutUint64(win7[0x80+8:], uint64(len(win7)))
+	binary.LittleEndian.PutUint64(win7[0x80+16:], filetime(modified))
+	binary.LittleEndian.PutUint32(win7[0x80+24:], insertFlagExecuted)
+	win7 = append(win7, regffs.EncodeUTF16(path)...)
+
+	// Windows 10, 0x34 byte header followed by "10ts" entries
+	entry := binary.LittleEndian.AppendUint16(nil, uint16(2*len(path)))
+	entry = append(entry, regffs.EncodeUTF16(path)...)
+	entry = binary.LittleEndian.AppendUint64(entry, filetime(modified))
+	entry = binary.LittleEndian.AppendUint32(entry, 0)
+	win10 := make([]byte, 0x34)
+	binary.LittleEndian.PutUint32(win10, 0x34)
+	win10 = append(win10, "10ts"...)
+	win10 = binary.LittleEndian.AppendUint32(win10, 0)
+	win10 = binary.LittleEndian.AppendUint32(win10, uint32(len(entry)))
+	win10 = append(win10, entry...)
+
+	tests := []struct {
+		name     string
+		data     []byte
+		format   string
+		size     uint64
+		executed *bool
+	}{
+		{"Windows XP", xp, FormatWindowsXP, 1234, nil},
+		{"Windows 7", win7, FormatWindows7, 0, new(bool)},
+		{"Windows 10", win10, FormatWindows10, 0, nil},
+	}
+	*tests[1].executed = true
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			format, entries, err := DecodeAppCompatCache(tt.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if format != tt.format {
+				t.Errorf("got format %s, want %s", format, tt.format)
+			}
+			if len(entries) != 1 {
+				t.Fatalf("got %d entries, want 1", len(entries))
+			}
+			e := entries[0]
+			if e.Path != path || !e.LastModified.Equal(modified) || e.Size != tt.size {
+				t.Errorf("wrong entry %+v", e)
+			}
+			if (e.Executed == nil) != (tt.executed == nil) || e.Executed != nil && *e.Executed != *tt.executed {
+				t.Errorf("got executed %v, want %v", e.Executed, tt.executed)
+			}
+		})
+	}
+}
@@ -0,0 +1,21 @@
+package artifacts
+
+import (
+	"fmt"
+
+	"github.com/forensicanalysis/regffs"
+)
+
+// CurrentControlSet returns the name of the current control set of a
+// SYSTEM hive, e.g. ControlSet001, as referenced by Select\Current.
+func CurrentControlSet(r *regffs.Regffs) (string, error) {
+	v, err := r.Value("Select/Current")
+	if err != nil {
+		return "", err
+	}
+	current, err := v.Uint()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("ControlSet%03d", current), nil
+}
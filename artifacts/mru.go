@@ -0,0 +1,245 @@
+package artifacts
+
+import (
+	"errors"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/forensicanalysis/regffs"
+	"github.com/forensicanalysis/regffs/shellbags"
+)
+
+// MRUOrder is the way the order of a MRU list is stored.
+type MRUOrder int
+
+const (
+	// OrderMRUList is a MRUList string value of value name letters.
+	OrderMRUList MRUOrder = iota
+	// OrderMRUListEx is a MRUListEx binary value of value name numbers.
+	OrderMRUListEx
+	// OrderNumbered are values named by a prefix and a number, e.g. url1,
+	// where lower numbers are more recent.
+	OrderNumbered
+)
+
+// MRUItem is the encoding of the MRU values.
+type MRUItem int
+
+const (
+	// ItemString is a string value.
+	ItemString MRUItem = iota
+	// ItemNameShellItem is an UTF-16 name followed by a shell item, as
+	// used by RecentDocs.
+	ItemNameShellItem
+	// ItemIDList is a shell item ID list.
+	ItemIDList
+	// ItemProgramIDList is an UTF-16 program name followed by a shell item
+	// ID list.
+	ItemProgramIDList
+	// ItemProgramPath is an UTF-16 program name followed by an UTF-16
+	// path.
+	ItemProgramPath
+	// ItemProgram is an UTF-16 program name.
+	ItemProgram
+)
+
+// MRUList describes a known MRU list.
+type MRUList struct {
+	Name string
+	// Key is relative to the NTUSER.DAT root.
+	Key   string
+	Order MRUOrder
+	Item  MRUItem
+	// Subkeys are read as additional lists, e.g. per file extension.
+	Subkeys bool
+	// Prefix is the value name prefix of OrderNumbered lists.
+	Prefix string
+}
+
+const explorerKey = "Software/Microsoft/Windows/CurrentVersion/Explorer"
+
+// MRULists are the MRU lists of NTUSER.DAT read by MRUs.
+var MRULists = []MRUList{
+	{Name: "RecentDocs", Key: explorerKey + "/RecentDocs", Order: OrderMRUListEx, Item: ItemNameShellItem, Subkeys: true},
+	{Name: "OpenSaveMRU", Key: explorerKey + "/ComDlg32/OpenSaveMRU", Order: OrderMRUList, Item: ItemString, Subkeys: true},
+	{Name: "OpenSavePidlMRU", Key: explorerKey + "/ComDlg32/OpenSavePidlMRU", Order: OrderMRUListEx, Item: ItemIDList, Subkeys: true},
+	{Name: "LastVisitedMRU", Key: explorerKey + "/ComDlg32/LastVisitedMRU", Order: OrderMRUList, Item: ItemProgramPath},
+	{Name: "LastVisitedPidlMRU", Key: explorerKey + "/ComDlg32/LastVisitedPidlMRU", Order: OrderMRUListEx, Item: ItemProgramIDList},
+	{Name: "LastVisitedPidlMRULegacy", Key: explorerKey + "/ComDlg32/LastVisitedPidlMRULegacy", Order: OrderMRUListEx, Item: ItemProgramIDList},
+	{Name: "CIDSizeMRU", Key: explorerKey + "/ComDlg32/CIDSizeMRU", Order: OrderMRUListEx, Item: ItemProgram},
+	{Name: "RunMRU", Key: explorerKey + "/RunMRU", Order: OrderMRUList, Item: ItemString},
+	{Name: "Map Network Drive MRU", Key: explorerKey + "/Map Network Drive MRU", Order: OrderMRUList, Item: ItemString},
+	{Name: "TypedPaths", Key: explorerKey + "/TypedPaths", Order: OrderNumbered, Item: ItemString, Prefix: "url"},
+	{Name: "TypedURLs", Key: "Software/Microsoft/Internet Explorer/TypedURLs", Order: OrderNumbered, Item: ItemString, Prefix: "url"},
+}
+
+// MRUEntry is an entry of a MRU list.
+type MRUEntry struct {
+	List string `json:"list"`
+	// Key is the path of the key relative to the hive root.
+	Key   string `json:"key"`
+	Value string `json:"value"`
+	// Position in the list, 0 is the most recently used entry.
+	Position int `json:"position"`
+	// Item is the decoded string, name or path.
+	Item string `json:"item"`
+	// Program is the program that used the item, only set for
+	// LastVisitedMRU, LastVisitedPidlMRU and CIDSizeMRU.
+	Program string `json:"program,omitempty"`
+	// KeyModTime is the last written time of the key, which usually is
+	// the time the entry at position 0 was used.
+	KeyModTime time.Time `json:"key_mtime"`
+}
+
+// MRUs reads all known MRU lists of a NTUSER.DAT hive.
+func MRUs(r *regffs.Regffs) ([]*MRUEntry, error) {
+	var entries []*MRUEntry
+	for _, list := range MRULists {
+		listEntries, err := ReadMRU(r, list)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, listEntries...)
+	}
+	return entries, nil
+}
+
+// ReadMRU reads the entries of a MRU list in their order. Values that are
+// not referenced by the order are skipped. Missing keys have no entries.
+func ReadMRU(r *regffs.Regffs, list MRUList) ([]*MRUEntry, error) {
+	keys := []string{list.Key}
+	if list.Subkeys {
+		subkeys, err := readDirIfExists(r, list.Key)
+		if err != nil {
+			return nil, err
+		}
+		for _, subkey := range subkeys {
+			if subkey.IsDir() {
+				keys = append(keys, path.Join(list.Key, subkey.Name()))
+			}
+		}
+	}
+
+	var entries []*MRUEntry
+	for _, key := range keys {
+		keyEntries, err := readMRUKey(r, list, key)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, keyEntries...)
+	}
+	return entries, nil
+}
+
+func readMRUKey(r *regffs.Regffs, list MRUList, key string) ([]*MRUEntry, error) {
+	info, err := fs.Stat(r, key)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	values, err := r.Values(key)
+	if err != nil {
+		return nil, err
+	}
+
+	var order []string
+	switch list.Order {
+	case OrderMRUList:
+		if v, ok := values["mrulist"]; ok {
+			order = MRUListOrder(v.String())
+		}
+	case OrderMRUListEx:
+		if v, ok := values["mrulistex"]; ok {
+			for _, n := range shellbags.MRUListEx(v.Data) {
+				order = append(order, strconv.Itoa(n))
+			}
+		}
+	case OrderNumbered:
+		order = numberedOrder(values, list.Prefix)
+	}
+
+	var entries []*MRUEntry
+	for _, name := range order {
+		v, ok := values[strings.ToLower(name)]
+		if !ok {
+			continue
+		}
+		entry := &MRUEntry{
+			List:       list.Name,
+			Key:        key,
+			Value:      name,
+			Position:   len(entries),
+			KeyModTime: info.ModTime(),
+		}
+		decodeMRUItem(entry, list.Item, v)
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// MRUListOrder decodes a MRUList value, a string of value name letters
+// with the most recent first.
+func MRUListOrder(mruList string) []string {
+	var order []string
+	for _, c := range mruList {
+		if c == 0 {
+			break
+		}
+		order = append(order, string(c))
+	}
+	return order
+}
+
+func numberedOrder(values map[string]*regffs.Value, prefix string) []string {
+	var numbers []int
+	for name := range values {
+		if n, err := strconv.Atoi(strings.TrimPrefix(name, prefix)); err == nil && strings.HasPrefix(name, prefix) {
+			numbers = append(numbers, n)
+		}
+	}
+	sort.Ints(numbers)
+	var order []string
+	for _, n := range numbers {
+		order = append(order, prefix+strconv.Itoa(n))
+	}
+	return order
+}
+
+func decodeMRUItem(entry *MRUEntry, item MRUItem, v *regffs.Value) {
+	switch item {
+	case ItemString:
+		// RunMRU entries end with \1
+		entry.Item = strings.TrimSuffix(v.String(), `\1`)
+	case ItemNameShellItem:
+		entry.Item, _ = utf16zRest(v.Data)
+	case ItemIDList:
+		items, _ := shellbags.ParseIDList(v.Data)
+		entry.Item = shellbags.JoinPath(items)
+	case ItemProgramIDList:
+		program, rest := utf16zRest(v.Data)
+		items, _ := shellbags.ParseIDList(rest)
+		entry.Program, entry.Item = program, shellbags.JoinPath(items)
+	case ItemProgramPath:
+		program, rest := utf16zRest(v.Data)
+		entry.Program, entry.Item = program, utf16z(rest)
+	case ItemProgram:
+		entry.Program, _ = utf16zRest(v.Data)
+	}
+}
+
+// utf16zRest decodes an UTF-16 string up to the first end-of-string
+// character and returns the data after it.
+func utf16zRest(b []byte) (string, []byte) {
+	for i := 0; i+1 < len(b); i += 2 {
+		if b[i] == 0 && b[i+1] == 0 {
+			return utf16z(b[:i]), b[i+2:]
+		}
+	}
+	return utf16z(b), nil
+}
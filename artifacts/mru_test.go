@@ -0,0 +1,57 @@
+package artifacts
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/forensicanalysis/regffs"
+)
+
+func TestMRUs(t *testing.T) {
+	entries, err := MRUs(openHive(t, "../testdata/NTUSER.DAT"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type entry struct {
+		list     string
+		key      string
+		position int
+		item     string
+	}
+	var got []entry
+	for _, e := range entries {
+		got = append(got, entry{e.List, e.Key, e.Position, e.Item})
+	}
+	recentDocs := "Software/Microsoft/Windows/CurrentVersion/Explorer/RecentDocs"
+	want := []entry{
+		{"RecentDocs", recentDocs, 0, "Administrator's Documents"},
+		{"RecentDocs", recentDocs, 1, "Not to be seen document.txt"},
+		{"RecentDocs", recentDocs, 2, "Very secret document.txt"},
+		{"RecentDocs", recentDocs + "/.txt", 0, "Not to be seen document.txt"},
+		{"RecentDocs", recentDocs + "/.txt", 1, "Very secret document.txt"},
+		{"RecentDocs", recentDocs + "/Folder", 0, "Administrator's Documents"},
+		{"TypedURLs", "Software/Microsoft/Internet Explorer/TypedURLs", 0, "http://firefox.com/"},
+		{"TypedURLs", "Software/Microsoft/Internet Explorer/TypedURLs", 1, "http://go.microsoft.com/fwlink/?LinkId=69157"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("MRUs() = %v, want %v", got, want)
+	}
+}
+
+func TestMRUListOrder(t *testing.T) {
+	if got, want := MRUListOrder("cab"), []string{"c", "a", "b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("MRUListOrder() = %v, want %v", got, want)
+	}
+}
+
+func TestDecodeMRUItem(t *testing.T) {
+	data := append(regffs.EncodeUTF16("notepad.exe"), 0, 0)
+	data = append(data, regffs.EncodeUTF16(`C:\Users\joe\Desktop`)...)
+	data = append(data, 0, 0)
+	entry := &MRUEntry{}
+	decodeMRUItem(entry, ItemProgramPath, &regffs.Value{Type: regffs.DataTypeEnum.RegBinary, Data: data})
+	if entry.Program != "notepad.exe" || entry.Item != `C:\Users\joe\Desktop` {
+		t.Errorf("decodeMRUItem() = %+v", entry)
+	}
+}
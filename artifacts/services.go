@@ -0,0 +1,228 @@
+package artifacts
+
+import (
+	"io/fs"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/forensicanalysis/regffs"
+)
+
+// Service start types.
+var ServiceStartTypes = map[uint32]string{
+	0: "Boot",
+	1: "System",
+	2: "Automatic",
+	3: "Manual",
+	4: "Disabled",
+}
+
+// Service type flags.
+var ServiceTypes = []struct {
+	Flag uint32
+	Name string
+}{
+	{0x001, "Kernel Driver"},
+	{0x002, "File System Driver"},
+	{0x004, "Adapter"},
+	{0x008, "Recognizer Driver"},
+	{0x010, "Own Process"},
+	{0x020, "Share Process"},
+	{0x040, "User Service"},
+	{0x080, "User Service Instance"},
+	{0x100, "Interactive Process"},
+}
+
+// Service is a service or driver of the Services key.
+type Service struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name,omitempty"`
+	Description string `json:"description,omitempty"`
+	ImagePath   string `json:"image_path,omitempty"`
+	// Start and Type are nil if the values are missing.
+	Start      *uint32 `json:"start,omitempty"`
+	Type       *uint32 `json:"type,omitempty"`
+	ObjectName string  `json:"object_name,omitempty"`
+	// ServiceDll is read from the Parameters subkey, or the service key on
+	// older systems.
+	ServiceDll string `json:"service_dll,omitempty"`
+	// KeyModTime is the last written time of the service key.
+	KeyModTime time.Time `json:"key_mtime"`
+	// Suspicious lists the reasons why the ImagePath or ServiceDll is
+	// suspicious.
+	Suspicious []string `json:"suspicious,omitempty"`
+}
+
+// StartName returns the name of the start type, e.g. Automatic.
+func (s *Service) StartName() string {
+	if s.Start == nil {
+		return ""
+	}
+	if name, ok := ServiceStartTypes[*s.Start]; ok {
+		return name
+	}
+	return "Unknown"
+}
+
+// TypeNames returns the names of the service type flags.
+func (s *Service) TypeNames() []string {
+	if s.Type == nil {
+		return nil
+	}
+	var names []string
+	for _, t := range ServiceTypes {
+		if *s.Type&t.Flag != 0 {
+			names = append(names, t.Name)
+		}
+	}
+	return names
+}
+
+// Services enumerates the services and drivers of the current control set
+// of a SYSTEM hive.
+func Services(r *regffs.Regffs) ([]*Service, error) {
+	controlSet, err := CurrentControlSet(r)
+	if err != nil {
+		return nil, err
+	}
+	servicesKey := path.Join(controlSet, "Services")
+	entries, err := fs.ReadDir(r, servicesKey)
+	if err != nil {
+		return nil, err
+	}
+
+	var services []*Service
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		service, err := readService(r, path.Join(servicesKey, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		services = append(services, service)
+	}
+	return services, nil
+}
+
+func readService(r *regffs.Regffs, key string) (*Service, error) {
+	info, err := fs.Stat(r, key)
+	if err != nil {
+		return nil, err
+	}
+	values, err := r.Values(key)
+	if err != nil {
+		return nil, err
+	}
+
+	service := &Service{Name: path.Base(key), KeyModTime: info.ModTime()}
+	for name, s := range map[string]*string{
+		"displayname": &service.DisplayName,
+		"description": &service.Description,
+		"imagepath":   &service.ImagePath,
+		"objectname":  &service.ObjectName,
+		"servicedll":  &service.ServiceDll,
+	} {
+		if v, ok := values[name]; ok {
+			*s = v.String()
+		}
+	}
+	for name, i := range map[string]**uint32{
+		"start": &service.Start,
+		"type":  &service.Type,
+	} {
+		if v, ok := values[name]; ok {
+			if u, err := v.Uint(); err == nil {
+				u32 := uint32(u)
+				*i = &u32
+			}
+		}
+	}
+
+	parameters, err := r.Values(path.Join(key, "Parameters"))
+	if err != nil {
+		return nil, err
+	}
+	if v, ok := parameters["servicedll"]; ok {
+		service.ServiceDll = v.String()
+	}
+
+	service.Suspicious = append(SuspiciousImagePath(service.ImagePath), SuspiciousImagePath(service.ServiceDll)...)
+	return service, nil
+}
+
+var suspiciousPaths = []struct {
+	substring string
+	reason    string
+}{
+	{`\temp\`, "temp directory"},
+	{`%temp%`, "temp directory"},
+	{`%tmp%`, "temp directory"},
+	{`\appdata\`, "user profile"},
+	{`%appdata%`, "user profile"},
+	{`%localappdata%`, "user profile"},
+	{`\users\public\`, "public user profile"},
+	{`\programdata\`, "ProgramData"},
+	{`%programdata%`, "ProgramData"},
+	{`\downloads\`, "downloads directory"},
+	{`\$recycle.bin\`, "recycle bin"},
+	{`\windows\tasks\`, "tasks directory"},
+	{`\windows\debug\`, "debug directory"},
+	{`\fonts\`, "fonts directory"},
+}
+
+var suspiciousCommands = []struct {
+	substring string
+	reason    string
+}{
+	{`cmd.exe`, "command shell"},
+	{`cmd /`, "command shell"},
+	{`%comspec%`, "command shell"},
+	{`powershell`, "PowerShell"},
+	{`pwsh`, "PowerShell"},
+	{`-enc`, "encoded command"},
+	{`mshta`, "script host"},
+	{`wscript`, "script host"},
+	{`cscript`, "script host"},
+	{`rundll32`, "rundll32"},
+	{`regsvr32`, "regsvr32"},
+	{`certutil`, "certutil"},
+	{`bitsadmin`, "bitsadmin"},
+	{`http://`, "URL"},
+	{`https://`, "URL"},
+}
+
+// SuspiciousImagePath returns the reasons why a service image path is
+// suspicious, e.g. because it is located in a temp directory or runs a
+// command shell.
+func SuspiciousImagePath(imagePath string) []string {
+	if imagePath == "" {
+		return nil
+	}
+	p := strings.ToLower(imagePath)
+
+	var reasons []string
+	seen := map[string]bool{}
+	add := func(reason string) {
+		if !seen[reason] {
+			seen[reason] = true
+			reasons = append(reasons, reason)
+		}
+	}
+	for _, s := range suspiciousPaths {
+		if strings.Contains(p, s.substring) {
+			add(s.reason)
+		}
+	}
+	for _, s := range suspiciousCommands {
+		if strings.Contains(p, s.substring) {
+			add(s.reason)
+		}
+	}
+	// UNC paths can also be arguments, e.g. of cmd.exe /c start
+	if strings.Contains(strings.ReplaceAll(p, `\\?\`, ""), `\\`) {
+		add("network path")
+	}
+	return reasons
+}
@@ -0,0 +1,97 @@
+package artifacts
+
+import (
+	"reflect"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/forensicanalysis/regffs"
+)
+
+func TestSuspiciousImagePath(t *testing.T) {
+	tests := []struct {
+		imagePath string
+		want      []string
+	}{
+		{`%SystemRoot%\System32\svchost.exe -k netsvcs`, nil},
+		{`system32\DRIVERS\tcpip.sys`, nil},
+		{`"C:\Program Files\Vendor\service.exe"`, nil},
+		{`C:\Users\joe\AppData\Local\Temp\svc.exe`, []string{"temp directory", "user profile"}},
+		{`%COMSPEC% /c powershell -enc SQBFAFgA`, []string{"command shell", "PowerShell", "encoded command"}},
+		{`cmd.exe /c start \\10.0.0.1\share\x.exe`, []string{"command shell", "network path"}},
+		{`\\10.0.0.1\share\x.exe`, []string{"network path"}},
+		{`\\?\C:\Windows\svc.exe`, nil},
+		{`C:\Windows\System32\rundll32.exe C:\ProgramData\x.dll,Start`, []string{"ProgramData", "rundll32"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.imagePath, func(t *testing.T) {
+			if got := SuspiciousImagePath(tt.imagePath); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SuspiciousImagePath() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServiceNames(t *testing.T) {
+	start, serviceType := uint32(2), uint32(0x120)
+	service := &Service{Start: &start, Type: &serviceType}
+	if got := service.StartName(); got != "Automatic" {
+		t.Errorf("StartName() = %s, want Automatic", got)
+	}
+	if got, want := service.TypeNames(), []string{"Share Process", "Interactive Process"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("TypeNames() = %v, want %v", got, want)
+	}
+}
+
+func TestServices(t *testing.T) {
+	modTime := time.Date(2021, 2, 3, 4, 5, 6, 0, time.UTC)
+	r := buildHive(t, fstest.MapFS{
+		"Select/Current":                                    mapValue(regffs.DwordValue(2)),
+		"ControlSet002/Services/Tcpip":                      mapKey(modTime),
+		"ControlSet002/Services/Tcpip/ImagePath":            mapValue(regffs.StringValue(`System32\drivers\tcpip.sys`)),
+		"ControlSet002/Services/Tcpip/Start":                mapValue(regffs.DwordValue(0)),
+		"ControlSet002/Services/Tcpip/Type":                 mapValue(regffs.DwordValue(1)),
+		"ControlSet002/Services/Tcpip/DisplayName":          mapValue(regffs.StringValue("TCP/IP Protocol Driver")),
+		"ControlSet002/Services/evil":                       mapKey(modTime),
+		"ControlSet002/Services/evil/ImagePath":             mapValue(regffs.StringValue(`%SystemRoot%\System32\svchost.exe -k netsvcs`)),
+		"ControlSet002/Services/evil/Start":                 mapValue(regffs.DwordValue(2)),
+		"ControlSet002/Services/evil/Type":                  mapValue(regffs.DwordValue(0x20)),
+		"ControlSet002/Services/evil/Parameters/ServiceDll": mapValue(regffs.StringValue(`C:\ProgramData\evil.dll`)),
+		// services of other control sets are ignored
+		"ControlSet001/Services/Old/Start": mapValue(regffs.DwordValue(3)),
+	})
+
+	services, err := Services(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uint32p := func(i uint32) *uint32 { return &i }
+	want := []*Service{
+		{
+			Name:        "Tcpip",
+			DisplayName: "TCP/IP Protocol Driver",
+			ImagePath:   `System32\drivers\tcpip.sys`,
+			Start:       uint32p(0),
+			Type:        uint32p(1),
+			KeyModTime:  modTime,
+		},
+		{
+			Name:       "evil",
+			ImagePath:  `%SystemRoot%\System32\svchost.exe -k netsvcs`,
+			Start:      uint32p(2),
+			Type:       uint32p(0x20),
+			ServiceDll: `C:\ProgramData\evil.dll`,
+			KeyModTime: modTime,
+			Suspicious: []string{"ProgramData"},
+		},
+	}
+	if len(services) != len(want) {
+		t.Fatalf("Services() = %d services, want %d", len(services), len(want))
+	}
+	for i, service := range services {
+		if !reflect.DeepEqual(service, want[i]) {
+			t.Errorf("Services()[%d] = %+v, want %+v", i, service, want[i])
+		}
+	}
+}
@@ -0,0 +1,382 @@
+package artifacts
+
+import (
+	"encoding/binary"
+	"errors"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/forensicanalysis/regffs"
+)
+
+// devicePropertiesGUID is the property set of the device install and
+// connection times.
+const devicePropertiesGUID = "{83da6326-97a6-4088-9453-a1923f573b29}"
+
+// Device properties of devicePropertiesGUID.
+const (
+	propertyFirstInstall = "0064"
+	propertyInstallDate  = "0065"
+	propertyLastArrival  = "0066"
+	propertyLastRemoval  = "0067"
+)
+
+// USBDevice is a USB storage device correlated from the SYSTEM, SOFTWARE
+// and NTUSER.DAT hives.
+type USBDevice struct {
+	// Class is the device type from USBSTOR, e.g. Disk or CdRom.
+	Class        string `json:"class"`
+	Vendor       string `json:"vendor"`
+	Product      string `json:"product"`
+	Revision     string `json:"revision"`
+	Serial       string `json:"serial"`
+	FriendlyName string `json:"friendly_name,omitempty"`
+	VendorID     string `json:"vendor_id,omitempty"`
+	ProductID    string `json:"product_id,omitempty"`
+	// ParentIDPrefix links the device to MountedDevices on Windows XP.
+	ParentIDPrefix string `json:"parent_id_prefix,omitempty"`
+	// DriveLetter and VolumeGUID are the last assignments of the
+	// MountedDevices key.
+	DriveLetter string `json:"drive_letter,omitempty"`
+	VolumeGUID  string `json:"volume_guid,omitempty"`
+	// VolumeName is the friendly name of the Windows Portable Devices key.
+	VolumeName   string    `json:"volume_name,omitempty"`
+	FirstInstall time.Time `json:"first_install"`
+	InstallDate  time.Time `json:"install_date"`
+	LastArrival  time.Time `json:"last_arrival"`
+	LastRemoval  time.Time `json:"last_removal"`
+	// KeyModTime is the last written time of the USBSTOR device key.
+	KeyModTime time.Time `json:"key_mtime"`
+	// Users that mounted the volume, according to their MountPoints2 key.
+	Users []*USBUser `json:"users,omitempty"`
+}
+
+// USBUser is a user that mounted a USB device.
+type USBUser struct {
+	Name string `json:"name"`
+	// KeyModTime is the last written time of the MountPoints2 key of the
+	// volume.
+	KeyModTime time.Time `json:"key_mtime"`
+}
+
+// USBDevices parses the USB storage devices of a SYSTEM hive. The software
+// hive and the NTUSER.DAT hives, mapped by user name, are optional and add
+// volume names and the users that mounted the devices.
+func USBDevices(system, software *regffs.Regffs, users map[string]*regffs.Regffs) ([]*USBDevice, error) {
+	controlSet, err := CurrentControlSet(system)
+	if err != nil {
+		return nil, err
+	}
+
+	devices, err := usbStorDevices(system, path.Join(controlSet, "Enum/USBSTOR"))
+	if err != nil {
+		return nil, err
+	}
+	if err := addUSBIDs(system, path.Join(controlSet, "Enum/USB"), devices); err != nil {
+		return nil, err
+	}
+	if err := addMountedDevices(system, devices); err != nil {
+		return nil, err
+	}
+	if software != nil {
+		if err := addPortableDevices(software, devices); err != nil {
+			return nil, err
+		}
+	}
+
+	var names []string
+	for name := range users {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := addMountPoints(users[name], name, devices); err != nil {
+			return nil, err
+		}
+	}
+	return devices, nil
+}
+
+func usbStorDevices(r *regffs.Regffs, usbStor string) ([]*USBDevice, error) {
+	classes, err := readDirIfExists(r, usbStor)
+	if err != nil {
+		return nil, err
+	}
+	var devices []*USBDevice
+	for _, class := range classes {
+		classKey := path.Join(usbStor, class.Name())
+		instances, err := fs.ReadDir(r, classKey)
+		if err != nil {
+			return nil, err
+		}
+		for _, instance := range instances {
+			if !instance.IsDir() {
+				continue
+			}
+			device := parseUSBStorName(class.Name())
+			device.Serial = USBSerial(instance.Name())
+			if err := readUSBStorInstance(r, path.Join(classKey, instance.Name()), device); err != nil {
+				return nil, err
+			}
+			devices = append(devices, device)
+		}
+	}
+	return devices, nil
+}
+
+// parseUSBStorName parses the device class key name of USBSTOR, e.g.
+// Disk&Ven_Kingston&Prod_DataTraveler_2.0&Rev_PMAP.
+func parseUSBStorName(name string) *USBDevice {
+	device := &USBDevice{}
+	for i, part := range strings.Split(name, "&") {
+		switch {
+		case i == 0:
+			device.Class = part
+		case strings.HasPrefix(part, "Ven_"):
+			device.Vendor = strings.TrimPrefix(part, "Ven_")
+		case strings.HasPrefix(part, "Prod_"):
+			device.Product = strings.TrimPrefix(part, "Prod_")
+		case strings.HasPrefix(part, "Rev_"):
+			device.Revision = strings.TrimPrefix(part, "Rev_")
+		}
+	}
+	return device
+}
+
+// USBSerial returns the serial number of a USBSTOR instance key name. The
+// instance suffix, e.g. &0, is removed. Devices without serial number have
+// an instance name generated by Windows, which contains an ampersand as
+// second character, and are returned unchanged.
+func USBSerial(instance string) string {
+	if len(instance) > 1 && instance[1] == '&' {
+		return instance
+	}
+	if i := strings.LastIndexByte(instance, '&'); i > 0 {
+		return instance[:i]
+	}
+	return instance
+}
+
+func readUSBStorInstance(r *regffs.Regffs, key string, device *USBDevice) error {
+	info, err := fs.Stat(r, key)
+	if err != nil {
+		return err
+	}
+	device.KeyModTime = info.ModTime()
+
+	for name, s := range map[string]*string{
+		"FriendlyName":   &device.FriendlyName,
+		"ParentIdPrefix": &device.ParentIDPrefix,
+	} {
+		v, err := r.Value(path.Join(key, name))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		*s = v.String()
+	}
+
+	properties := path.Join(key, "Properties", devicePropertiesGUID)
+	for id, t := range map[string]*time.Time{
+		propertyFirstInstall: &device.FirstInstall,
+		propertyInstallDate:  &device.InstallDate,
+		propertyLastArrival:  &device.LastArrival,
+		propertyLastRemoval:  &device.LastRemoval,
+	} {
+		// Windows 7 names the keys 00000064, later versions 0064
+		for _, name := range []string{id, "0000" + id} {
+			propertyTime, err := readPropertyTime(r, path.Join(properties, name), 0)
+			if err != nil {
+				return err
+			}
+			if !propertyTime.IsZero() {
+				*t = propertyTime
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// readPropertyTime reads a FILETIME device property. Windows 7 stores it
+// in the Data value of a 00000000 subkey, later versions in the default
+// value of the property key.
+func readPropertyTime(r *regffs.Regffs, key string, depth int) (time.Time, error) {
+	entries, err := readDirIfExists(r, key)
+	if err != nil || depth > 1 {
+		return time.Time{}, err
+	}
+	for _, entry := range entries {
+		f, ok := entry.(*regffs.File)
+		if !ok {
+			continue
+		}
+		if f.IsDir() {
+			t, err := readPropertyTime(r, path.Join(key, f.Name()), depth+1)
+			if err != nil || !t.IsZero() {
+				return t, err
+			}
+			continue
+		}
+		v, err := f.Value()
+		if err != nil {
+			return time.Time{}, err
+		}
+		if len(v.Data) == 8 {
+			return regffs.FiletimeToTime(binary.LittleEndian.Uint64(v.Data)), nil
+		}
+	}
+	return time.Time{}, nil
+}
+
+// addUSBIDs adds the vendor and product IDs of the Enum\USB key, e.g.
+// VID_0951&PID_1607, which has instance keys named by the same serial.
+func addUSBIDs(r *regffs.Regffs, usb string, devices []*USBDevice) error {
+	ids, err := readDirIfExists(r, usb)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		vendorID, productID := parseUSBID(id.Name())
+		if vendorID == "" {
+			continue
+		}
+		instances, err := fs.ReadDir(r, path.Join(usb, id.Name()))
+		if err != nil {
+			return err
+		}
+		for _, instance := range instances {
+			for _, device := range devices {
+				if strings.EqualFold(device.Serial, instance.Name()) {
+					device.VendorID, device.ProductID = vendorID, productID
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func parseUSBID(name string) (vendorID, productID string) {
+	for _, part := range strings.Split(name, "&") {
+		switch {
+		case strings.HasPrefix(part, "VID_"):
+			vendorID = strings.TrimPrefix(part, "VID_")
+		case strings.HasPrefix(part, "PID_"):
+			productID = strings.TrimPrefix(part, "PID_")
+		}
+	}
+	return vendorID, productID
+}
+
+// addMountedDevices adds the drive letters and volume GUIDs of the
+// MountedDevices key. Its values map \DosDevices\E: and \??\Volume{GUID}
+// to the device path, which contains the serial or, on Windows XP, the
+// ParentIdPrefix.
+func addMountedDevices(r *regffs.Regffs, devices []*USBDevice) error {
+	entries, err := readDirIfExists(r, "MountedDevices")
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		f, ok := entry.(*regffs.File)
+		if !ok || f.IsDir() {
+			continue
+		}
+		v, err := f.Value()
+		if err != nil {
+			return err
+		}
+		devicePath, err := regffs.DecodeUTF16(v.Data[:len(v.Data)&^1])
+		if err != nil {
+			continue
+		}
+		for _, device := range devices {
+			if !device.mountedAs(devicePath) {
+				continue
+			}
+			switch name := f.Name(); {
+			case strings.HasPrefix(name, `\DosDevices\`):
+				device.DriveLetter = strings.TrimPrefix(name, `\DosDevices\`)
+			case strings.HasPrefix(name, `\??\Volume`):
+				device.VolumeGUID = strings.TrimPrefix(name, `\??\Volume`)
+			}
+		}
+	}
+	return nil
+}
+
+func (d *USBDevice) mountedAs(devicePath string) bool {
+	devicePath = strings.ToUpper(devicePath)
+	if strings.Contains(devicePath, "#"+strings.ToUpper(d.Serial)+"&") || strings.Contains(devicePath, "#"+strings.ToUpper(d.Serial)+"#") {
+		return true
+	}
+	return d.ParentIDPrefix != "" && strings.Contains(devicePath, "#"+strings.ToUpper(d.ParentIDPrefix)+"&")
+}
+
+// addPortableDevices adds the volume names of the Windows Portable Devices
+// key of a SOFTWARE hive, whose subkeys are named by the device path.
+func addPortableDevices(r *regffs.Regffs, devices []*USBDevice) error {
+	key := "Microsoft/Windows Portable Devices/Devices"
+	entries, err := readDirIfExists(r, key)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		for _, device := range devices {
+			if !device.mountedAs(entry.Name()) {
+				continue
+			}
+			v, err := r.Value(path.Join(key, entry.Name(), "FriendlyName"))
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			device.VolumeName = v.String()
+		}
+	}
+	return nil
+}
+
+// addMountPoints adds the user to devices whose volume GUID is listed in
+// the MountPoints2 key of the NTUSER.DAT hive.
+func addMountPoints(r *regffs.Regffs, user string, devices []*USBDevice) error {
+	key := "Software/Microsoft/Windows/CurrentVersion/Explorer/MountPoints2"
+	entries, err := readDirIfExists(r, key)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		for _, device := range devices {
+			if device.VolumeGUID == "" || !strings.EqualFold(device.VolumeGUID, entry.Name()) {
+				continue
+			}
+			info, err := entry.Info()
+			if err != nil {
+				return err
+			}
+			device.Users = append(device.Users, &USBUser{Name: user, KeyModTime: info.ModTime()})
+		}
+	}
+	return nil
+}
+
+func readDirIfExists(r *regffs.Regffs, key string) ([]fs.DirEntry, error) {
+	entries, err := fs.ReadDir(r, key)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	return entries, err
+}
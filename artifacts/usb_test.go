@@ -0,0 +1,179 @@
+package artifacts
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/forensicanalysis/regffs"
+)
+
+// buildHive builds a hive of fsys with regffs.Build.
+func buildHive(t *testing.T, fsys fstest.MapFS) *regffs.Regffs {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := regffs.Build(&buf, fsys); err != nil {
+		t.Fatal(err)
+	}
+	r, err := regffs.New(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func mapValue(v *regffs.Value) *fstest.MapFile {
+	return &fstest.MapFile{Data: v.Data, Sys: v.Type}
+}
+
+func mapKey(modTime time.Time) *fstest.MapFile {
+	return &fstest.MapFile{Mode: 0o755 | os.ModeDir, ModTime: modTime}
+}
+
+func TestUSBDevices(t *testing.T) {
+	installed := time.Date(2020, 5, 17, 10, 11, 12, 0, time.UTC)
+	arrived := time.Date(2020, 6, 1, 8, 0, 0, 0, time.UTC)
+	removed := time.Date(2020, 6, 1, 9, 30, 0, 0, time.UTC)
+	mounted := time.Date(2020, 6, 1, 8, 0, 5, 0, time.UTC)
+	property := func(t time.Time) *fstest.MapFile {
+		return &fstest.MapFile{Data: regffs.QwordValue(filetime(t)).Data, Sys: regffs.DataTypeEnum.RegBinary}
+	}
+	mountedDevice := func(devicePath string) *fstest.MapFile {
+		return &fstest.MapFile{Data: regffs.EncodeUTF16(devicePath), Sys: regffs.DataTypeEnum.RegBinary}
+	}
+
+	const (
+		storName   = "Disk&Ven_Kingston&Prod_DataTraveler_2.0&Rev_PMAP"
+		instance   = "ControlSet002/Enum/USBSTOR/" + storName + "/5B6B1A0000F3&0"
+		properties = instance + "/Properties/" + devicePropertiesGUID
+		devicePath = `\??\USBSTOR#` + storName + `#5B6B1A0000F3&0#{53f56307-b6bf-11d0-94f2-00a0c91efb8b}`
+		volumeGUID = "{6a1b2c3d-0000-11ea-9b7c-806e6f6e6963}"
+		portable   = `WPDBUSENUMROOT#UMB#2&37C186B&0&STORAGE#VOLUME#_??_USBSTOR#DISK&VEN_KINGSTON&PROD_DATATRAVELER_2.0&REV_PMAP#5B6B1A0000F3&0#`
+	)
+	system := buildHive(t, fstest.MapFS{
+		"Select/Current":           mapValue(regffs.DwordValue(2)),
+		instance:                   mapKey(installed),
+		instance + "/FriendlyName": mapValue(regffs.StringValue("Kingston DataTraveler 2.0 USB Device")),
+		// Windows 10 and Windows 7 property keys
+		properties + "/0064/(default)":                          property(installed),
+		properties + "/0066/(default)":                          property(arrived),
+		properties + "/00000067/00000000/Data":                  property(removed),
+		"ControlSet002/Enum/USB/VID_0951&PID_1607/5B6B1A0000F3": mapKey(installed),
+		// devices of other control sets are ignored
+		"ControlSet001/Enum/USBSTOR/Disk&Ven_SanDisk&Prod_Cruzer&Rev_1.00/4C530001&0": mapKey(installed),
+		`MountedDevices/\DosDevices\E:`:                                               mountedDevice(devicePath),
+		`MountedDevices/\??\Volume` + volumeGUID:                                      mountedDevice(devicePath),
+		`MountedDevices/\DosDevices\C:`:                                               mountedDevice(`\??\SCSI#Disk&Ven_NVMe#5&1a2b3c4d&0&000000#{53f56307-b6bf-11d0-94f2-00a0c91efb8b}`),
+	})
+	software := buildHive(t, fstest.MapFS{
+		"Microsoft/Windows Portable Devices/Devices/" + portable + "/FriendlyName": mapValue(regffs.StringValue("KINGSTON")),
+	})
+	const mountPoints = "Software/Microsoft/Windows/CurrentVersion/Explorer/MountPoints2/"
+	users := map[string]*regffs.Regffs{
+		"joe":  buildHive(t, fstest.MapFS{mountPoints + volumeGUID: mapKey(mounted)}),
+		"jane": buildHive(t, fstest.MapFS{mountPoints + "{00000000-0000-0000-0000-000000000000}": mapKey(mounted)}),
+	}
+
+	got, err := USBDevices(system, software, users)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &USBDevice{
+		Class:        "Disk",
+		Vendor:       "Kingston",
+		Product:      "DataTraveler_2.0",
+		Revision:     "PMAP",
+		Serial:       "5B6B1A0000F3",
+		FriendlyName: "Kingston DataTraveler 2.0 USB Device",
+		VendorID:     "0951",
+		ProductID:    "1607",
+		DriveLetter:  "E:",
+		VolumeGUID:   volumeGUID,
+		VolumeName:   "KINGSTON",
+		FirstInstall: installed,
+		LastArrival:  arrived,
+		LastRemoval:  removed,
+		KeyModTime:   installed,
+		Users:        []*USBUser{{Name: "joe", KeyModTime: mounted}},
+	}
+	if len(got) != 1 {
+		t.Fatalf("USBDevices() = %d devices, want 1", len(got))
+	}
+	if !reflect.DeepEqual(got[0], want) {
+		t.Errorf("USBDevices() = %+v, want %+v", got[0], want)
+	}
+}
+
+func TestParseUSBStorName(t *testing.T) {
+	device := parseUSBStorName("Disk&Ven_Kingston&Prod_DataTraveler_2.0&Rev_PMAP")
+	if device.Class != "Disk" || device.Vendor != "Kingston" || device.Product != "DataTraveler_2.0" || device.Revision != "PMAP" {
+		t.Errorf("parseUSBStorName() = %+v", device)
+	}
+}
+
+func TestUSBSerial(t *testing.T) {
+	tests := []struct {
+		instance string
+		want     string
+	}{
+		{"5B6B1A0000F3&0", "5B6B1A0000F3"},
+		{"0019E06B9C85F9A0F7550CD7&0", "0019E06B9C85F9A0F7550CD7"},
+		{"7&326659cd&0", "7&326659cd&0"},
+	}
+	for _, tt := range tests {
+		if got := USBSerial(tt.instance); got != tt.want {
+			t.Errorf("USBSerial(%q) = %q, want %q", tt.instance, got, tt.want)
+		}
+	}
+}
+
+func TestUSBDeviceMountedAs(t *testing.T) {
+	tests := []struct {
+		name       string
+		device     *USBDevice
+		devicePath string
+		want       bool
+	}{
+		{
+			"serial",
+			&USBDevice{Serial: "5B6B1A0000F3"},
+			`\??\USBSTOR#Disk&Ven_Kingston&Prod_DataTraveler_2.0&Rev_PMAP#5B6B1A0000F3&0#{53f56307-b6bf-11d0-94f2-00a0c91efb8b}`,
+			true,
+		},
+		{
+			"parent id prefix",
+			&USBDevice{Serial: "6&2a1bc2ad&0", ParentIDPrefix: "7&326659cd&0"},
+			`\??\STORAGE#RemovableMedia#7&326659cd&0&RM#{53f5630d-b6bf-11d0-94f2-00a0c91efb8b}`,
+			true,
+		},
+		{
+			"portable device",
+			&USBDevice{Serial: "5B6B1A0000F3"},
+			`WPDBUSENUMROOT#UMB#2&37C186B&0&STORAGE#VOLUME#_??_USBSTOR#DISK&VEN_KINGSTON&PROD_DATATRAVELER_2.0&REV_PMAP#5B6B1A0000F3&0#`,
+			true,
+		},
+		{
+			"other device",
+			&USBDevice{Serial: "5B6B1A0000F3"},
+			`\??\USBSTOR#Disk&Ven_SanDisk&Prod_Cruzer&Rev_1.00#4C530001&0#{53f56307-b6bf-11d0-94f2-00a0c91efb8b}`,
+			false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.device.mountedAs(tt.devicePath); got != tt.want {
+				t.Errorf("mountedAs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseUSBID(t *testing.T) {
+	vendorID, productID := parseUSBID("VID_0951&PID_1607")
+	if vendorID != "0951" || productID != "1607" {
+		t.Errorf("parseUSBID() = %s, %s, want 0951, 1607", vendorID, productID)
+	}
+}
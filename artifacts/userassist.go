@@ -0,0 +1,149 @@
+// Package artifacts decodes forensic artifacts from Windows registry hives
+// opened with regffs.
+package artifacts
+
+import (
+	"encoding/binary"
+	"errors"
+	"io/fs"
+	"path"
+	"time"
+
+	"github.com/forensicanalysis/regffs"
+)
+
+const userAssistKey = "Software/Microsoft/Windows/CurrentVersion/Explorer/UserAssist"
+
+// UserAssistGUIDs names the well known UserAssist GUIDs.
+var UserAssistGUIDs = map[string]string{
+	"{75048700-EF1F-11D0-9888-006097DEACF9}": "Active Desktop",
+	"{5E6AB780-7743-11CF-A12B-00AA004AE837}": "Internet Toolbar",
+	"{CEBFF5CD-ACE2-4F4F-9178-9926F41749EA}": "Executable File Execution",
+	"{F4E57C4B-2036-45F0-A9AB-443BCFE33D9F}": "Shortcut File Execution",
+	"{F2A1CB5A-E3CC-4A2E-AF9D-505A7009D442}": "Windows 7 Taskbar",
+	"{FA99DFC7-6AC2-453A-A5E2-5E2AFF4507BD}": "Application File Execution",
+}
+
+// UserAssistEntry is a decoded entry of a UserAssist Count key.
+type UserAssistEntry struct {
+	GUID string `json:"guid"`
+	// Name is the ROT13 decoded value name.
+	Name string `json:"name"`
+	// Version of the entry format, 3 up to Windows Vista, 5 since Windows 7.
+	Version    int           `json:"version"`
+	Session    uint32        `json:"session"`
+	RunCount   uint32        `json:"run_count"`
+	FocusCount uint32        `json:"focus_count,omitempty"`
+	FocusTime  time.Duration `json:"focus_time,omitempty"`
+	LastRun    time.Time     `json:"last_run"`
+	// KeyModTime is the last written time of the Count key.
+	KeyModTime time.Time `json:"key_mtime"`
+}
+
+// UserAssist decodes all UserAssist entries of a NTUSER.DAT hive.
+func UserAssist(r *regffs.Regffs) ([]*UserAssistEntry, error) {
+	guids, err := fs.ReadDir(r, userAssistKey)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []*UserAssistEntry
+	for _, guid := range guids {
+		if !guid.IsDir() {
+			continue
+		}
+		guidKey := path.Join(userAssistKey, guid.Name())
+
+		version := 0
+		if v, err := r.Value(path.Join(guidKey, "Version")); err == nil {
+			if i, err := v.Uint(); err == nil {
+				version = int(i)
+			}
+		}
+
+		countKey := path.Join(guidKey, "Count")
+		info, err := fs.Stat(r, countKey)
+		if err != nil {
+			continue
+		}
+		values, err := fs.ReadDir(r, countKey)
+		if err != nil {
+			return nil, err
+		}
+		for _, value := range values {
+			if value.IsDir() {
+				continue
+			}
+			name := ROT13(value.Name())
+			if name == "UEME_CTLSESSION" {
+				continue
+			}
+			f, ok := value.(*regffs.File)
+			if !ok {
+				continue
+			}
+			v, err := f.Value()
+			if err != nil {
+				return nil, err
+			}
+			entry, ok := decodeUserAssist(v.Data, version)
+			if !ok {
+				continue
+			}
+			entry.GUID = guid.Name()
+			entry.Name = name
+			entry.KeyModTime = info.ModTime()
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+func decodeUserAssist(b []byte, version int) (*UserAssistEntry, bool) {
+	if version == 0 {
+		switch len(b) {
+		case 16:
+			version = 3
+		case 72:
+			version = 5
+		}
+	}
+
+	entry := &UserAssistEntry{Version: version}
+	switch {
+	case version == 3 && len(b) >= 16:
+		entry.Session = binary.LittleEndian.Uint32(b[0:])
+		entry.RunCount = binary.LittleEndian.Uint32(b[4:])
+		// the run count starts at 5
+		if entry.RunCount >= 5 {
+			entry.RunCount -= 5
+		}
+		entry.LastRun = regffs.FiletimeToTime(binary.LittleEndian.Uint64(b[8:]))
+	case version == 5 && len(b) >= 68:
+		entry.Session = binary.LittleEndian.Uint32(b[0:])
+		entry.RunCount = binary.LittleEndian.Uint32(b[4:])
+		entry.FocusCount = binary.LittleEndian.Uint32(b[8:])
+		entry.FocusTime = time.Duration(binary.LittleEndian.Uint32(b[12:])) * time.Millisecond
+		entry.LastRun = regffs.FiletimeToTime(binary.LittleEndian.Uint64(b[60:]))
+	default:
+		return nil, false
+	}
+	return entry, true
+}
+
+// ROT13 decodes the ROT13 encoded UserAssist value names.
+func ROT13(s string) string {
+	b := []byte(s)
+	for i, c := range b {
+		switch {
+		case c >= 'a' && c <= 'z':
+			b[i] = 'a' + (c-'a'+13)%26
+		case c >= 'A' && c <= 'Z':
+			b[i] = 'A' + (c-'A'+13)%26
+		}
+	}
+	return string(b)
+}
@@ -0,0 +1,71 @@
+package artifacts
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/forensicanalysis/regffs"
+)
+
+func openHive(t *testing.T, name string) *regffs.Regffs {
+	t.Helper()
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+
+	fsys, err := regffs.New(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fsys
+}
+
+func TestUserAssist(t *testing.T) {
+	entries, err := UserAssist(openHive(t, "../testdata/NTUSER.DAT"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		runCount uint32
+		lastRun  time.Time
+	}{
+		{`UEME_RUNPATH:C:\Program Files\Internet Explorer\iexplore.exe`, 1, time.Date(2009, 8, 4, 15, 13, 42, 0, time.UTC)},
+		{`UEME_RUNPATH:C:\WINDOWS\system32\NOTEPAD.EXE`, 2, time.Date(2009, 8, 4, 15, 19, 23, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, entry := range entries {
+				if entry.Name != tt.name {
+					continue
+				}
+				if entry.Version != 3 || entry.RunCount != tt.runCount || !entry.LastRun.Truncate(time.Second).Equal(tt.lastRun) {
+					t.Errorf("got version %d, count %d, last run %s", entry.Version, entry.RunCount, entry.LastRun)
+				}
+				return
+			}
+			t.Errorf("entry not found")
+		})
+	}
+}
+
+func TestDecodeUserAssistVersion5(t *testing.T) {
+	b := make([]byte, 72)
+	b[4] = 7     // run count
+	b[8] = 3     // focus count
+	b[12] = 0xe8 // focus time 1000 ms
+	b[13] = 0x03
+	copy(b[60:], []byte{0x00, 0x80, 0x3e, 0xd5, 0xde, 0xb1, 0x9d, 0x01}) // 1970-01-01
+
+	entry, ok := decodeUserAssist(b, 5)
+	if !ok {
+		t.Fatal("not decoded")
+	}
+	if entry.RunCount != 7 || entry.FocusCount != 3 || entry.FocusTime != time.Second || !entry.LastRun.Equal(time.Unix(0, 0)) {
+		t.Errorf("wrong entry %+v", entry)
+	}
+}
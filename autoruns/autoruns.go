@@ -0,0 +1,228 @@
+// Package autoruns finds persistence and autostart entries in SYSTEM,
+// SOFTWARE and NTUSER.DAT hives opened with regffs.
+package autoruns
+
+import (
+	"errors"
+	"io/fs"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/forensicanalysis/regffs"
+	"github.com/forensicanalysis/regffs/artifacts"
+)
+
+// Hive types of the locations.
+const (
+	System   = "SYSTEM"
+	Software = "SOFTWARE"
+	NTUser   = "NTUSER.DAT"
+)
+
+// Location is a key that is checked for autostart entries.
+type Location struct {
+	// Category groups related locations, e.g. Run or Services.
+	Category string
+	Hive     string
+	// Key is the path of the key relative to the hive root. Path elements
+	// of "*" match all subkeys. Keys of SYSTEM hives are relative to the
+	// current control set.
+	Key string
+	// Value is the name of the value holding the entry. All values of the
+	// key are entries if it is empty. Entries of default values are named
+	// after their key, e.g. the CLSID of a browser helper object.
+	Value string
+}
+
+// Locations are the autostart locations checked by Autoruns.
+var Locations = []Location{
+	{"Run", Software, "Microsoft/Windows/CurrentVersion/Run", ""},
+	{"Run", Software, "Microsoft/Windows/CurrentVersion/RunOnce", ""},
+	{"Run", Software, "Microsoft/Windows/CurrentVersion/RunOnceEx/*", ""},
+	{"Run", Software, "Microsoft/Windows/CurrentVersion/RunServices", ""},
+	{"Run", Software, "Microsoft/Windows/CurrentVersion/RunServicesOnce", ""},
+	{"Run", Software, "Microsoft/Windows/CurrentVersion/Policies/Explorer/Run", ""},
+	{"Run", Software, "Wow6432Node/Microsoft/Windows/CurrentVersion/Run", ""},
+	{"Run", Software, "Wow6432Node/Microsoft/Windows/CurrentVersion/RunOnce", ""},
+	{"Run", NTUser, "Software/Microsoft/Windows/CurrentVersion/Run", ""},
+	{"Run", NTUser, "Software/Microsoft/Windows/CurrentVersion/RunOnce", ""},
+	{"Run", NTUser, "Software/Microsoft/Windows/CurrentVersion/RunServices", ""},
+	{"Run", NTUser, "Software/Microsoft/Windows/CurrentVersion/RunServicesOnce", ""},
+	{"Run", NTUser, "Software/Microsoft/Windows/CurrentVersion/Policies/Explorer/Run", ""},
+	{"Run", NTUser, "Software/Wow6432Node/Microsoft/Windows/CurrentVersion/Run", ""},
+	{"Run", NTUser, "Software/Microsoft/Windows NT/CurrentVersion/Windows", "Load"},
+	{"Run", NTUser, "Software/Microsoft/Windows NT/CurrentVersion/Windows", "Run"},
+
+	{"Winlogon", Software, "Microsoft/Windows NT/CurrentVersion/Winlogon", "Shell"},
+	{"Winlogon", Software, "Microsoft/Windows NT/CurrentVersion/Winlogon", "Userinit"},
+	{"Winlogon", Software, "Microsoft/Windows NT/CurrentVersion/Winlogon", "Taskman"},
+	{"Winlogon", Software, "Microsoft/Windows NT/CurrentVersion/Winlogon", "AppSetup"},
+	{"Winlogon", Software, "Microsoft/Windows NT/CurrentVersion/Winlogon/Notify/*", "DLLName"},
+	{"Winlogon", NTUser, "Software/Microsoft/Windows NT/CurrentVersion/Winlogon", "Shell"},
+
+	{"Services", System, "Services/*", "ImagePath"},
+	{"Services", System, "Services/*/Parameters", "ServiceDll"},
+	{"Session Manager", System, "Control/Session Manager", "BootExecute"},
+	{"Session Manager", System, "Control/Session Manager", "SetupExecute"},
+	{"Lsa", System, "Control/Lsa", "Authentication Packages"},
+	{"Lsa", System, "Control/Lsa", "Notification Packages"},
+	{"Lsa", System, "Control/Lsa", "Security Packages"},
+	{"Print Monitors", System, "Control/Print/Monitors/*", "Driver"},
+
+	{"Image Hijacks", Software, "Microsoft/Windows NT/CurrentVersion/Image File Execution Options/*", "Debugger"},
+	{"Image Hijacks", Software, "Wow6432Node/Microsoft/Windows NT/CurrentVersion/Image File Execution Options/*", "Debugger"},
+	{"Image Hijacks", Software, "Microsoft/Windows NT/CurrentVersion/SilentProcessExit/*", "MonitorProcess"},
+	{"AppInit", Software, "Microsoft/Windows NT/CurrentVersion/Windows", "AppInit_DLLs"},
+	{"AppInit", Software, "Wow6432Node/Microsoft/Windows NT/CurrentVersion/Windows", "AppInit_DLLs"},
+	{"Active Setup", Software, "Microsoft/Active Setup/Installed Components/*", "StubPath"},
+	{"Active Setup", Software, "Wow6432Node/Microsoft/Active Setup/Installed Components/*", "StubPath"},
+	{"Explorer", Software, "Microsoft/Windows/CurrentVersion/Explorer/ShellServiceObjectDelayLoad", ""},
+	{"Explorer", Software, "Microsoft/Windows/CurrentVersion/Explorer/Browser Helper Objects/*", "(default)"},
+	{"Scheduled Tasks", Software, "Microsoft/Windows NT/CurrentVersion/Schedule/TaskCache/Tasks/*", "Path"},
+	{"Command Processor", Software, "Microsoft/Command Processor", "AutoRun"},
+	{"Command Processor", NTUser, "Software/Microsoft/Command Processor", "AutoRun"},
+}
+
+// Entry is an autostart entry.
+type Entry struct {
+	Category string `json:"category"`
+	// Key is the path of the key relative to the hive root.
+	Key   string `json:"key"`
+	Value string `json:"value"`
+	// Command is the value data, usually a command line or a DLL path.
+	Command string `json:"command"`
+	// KeyModTime is the last written time of the key.
+	KeyModTime time.Time `json:"key_mtime"`
+}
+
+// Autoruns checks all locations for autostart entries. As the locations
+// of different hive types do not overlap, hives are not required to be of
+// a specific type. Names are compared case insensitive, values without
+// data and values that can not be read are skipped.
+func Autoruns(r *regffs.Regffs) ([]*Entry, error) {
+	controlSet, err := artifacts.CurrentControlSet(r)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	var entries []*Entry
+	for _, location := range Locations {
+		key := location.Key
+		if location.Hive == System {
+			if controlSet == "" {
+				continue
+			}
+			key = path.Join(controlSet, key)
+		}
+		keys, err := expand(r, key)
+		if err != nil {
+			return nil, err
+		}
+		for _, k := range keys {
+			locationEntries, err := readEntries(k, location)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, locationEntries...)
+		}
+	}
+	return entries, nil
+}
+
+type key struct {
+	path string
+	file *regffs.File
+}
+
+// expand resolves the key path case insensitive and with wildcards.
+func expand(r *regffs.Regffs, keyPath string) ([]key, error) {
+	root, err := r.Open(".")
+	if err != nil {
+		return nil, err
+	}
+	keys := []key{{"", root.(*regffs.File)}}
+	for _, element := range strings.Split(keyPath, "/") {
+		var next []key
+		for _, k := range keys {
+			subkeys, err := subkeys(k.file)
+			if err != nil {
+				return nil, err
+			}
+			for _, subkey := range subkeys {
+				if element == "*" || strings.EqualFold(element, subkey.Name()) {
+					next = append(next, key{path.Join(k.path, subkey.Name()), subkey})
+				}
+			}
+		}
+		keys = next
+	}
+	return keys, nil
+}
+
+func subkeys(f *regffs.File) ([]*regffs.File, error) {
+	entries, err := f.ReadDir(-1)
+	if err != nil {
+		return nil, err
+	}
+	var keys []*regffs.File
+	for _, entry := range entries {
+		if f, ok := entry.(*regffs.File); ok && f.IsDir() {
+			keys = append(keys, f)
+		}
+	}
+	return keys, nil
+}
+
+func readEntries(k key, location Location) ([]*Entry, error) {
+	files, err := k.file.ReadDir(-1)
+	if err != nil {
+		return nil, err
+	}
+	var entries []*Entry
+	for _, file := range files {
+		f, ok := file.(*regffs.File)
+		if !ok || f.IsDir() {
+			continue
+		}
+		if location.Value != "" && !strings.EqualFold(location.Value, f.Name()) {
+			continue
+		}
+		value, err := f.Value()
+		if err != nil {
+			continue
+		}
+		name := f.Name()
+		if name == "(default)" {
+			name = path.Base(k.path)
+		}
+		for _, command := range commands(value) {
+			entries = append(entries, &Entry{
+				Category:   location.Category,
+				Key:        k.path,
+				Value:      name,
+				Command:    command,
+				KeyModTime: k.file.ModTime(),
+			})
+		}
+	}
+	return entries, nil
+}
+
+// commands returns the non-empty strings of a value, REG_MULTI_SZ values
+// can contain multiple commands.
+func commands(value *regffs.Value) []string {
+	var strs []string
+	if value.Type == regffs.DataTypeEnum.RegMultiSz {
+		strs, _ = value.Strings()
+	} else {
+		strs = []string{value.String()}
+	}
+	var commands []string
+	for _, s := range strs {
+		if s = strings.TrimSpace(s); s != "" {
+			commands = append(commands, s)
+		}
+	}
+	return commands
+}
@@ -0,0 +1,153 @@
+package autoruns
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"reflect"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/forensicanalysis/regffs"
+)
+
+func TestAutoruns(t *testing.T) {
+	f, err := os.Open("../testdata/NTUSER.DAT")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	fsys, err := regffs.New(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := Autoruns(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("Autoruns() returned %d entries, want 1", len(entries))
+	}
+	entry := entries[0]
+	want := &Entry{
+		Category: "Run",
+		Key:      "Software/Microsoft/Windows/CurrentVersion/Run",
+		Value:    "ctfmon.exe",
+		Command:  `C:\WINDOWS\system32\ctfmon.exe`,
+	}
+	if entry.Category != want.Category || entry.Key != want.Key || entry.Value != want.Value || entry.Command != want.Command {
+		t.Errorf("Autoruns() = %+v, want %+v", entry, want)
+	}
+	if entry.KeyModTime.Before(time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("KeyModTime = %s", entry.KeyModTime)
+	}
+}
+
+// buildHive builds a hive of fsys with regffs.Build. The data of the value
+// broken, if any, is pointed outside of the hive.
+func buildHive(t *testing.T, fsys fstest.MapFS, broken string) *regffs.Regffs {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := regffs.Build(&buf, fsys); err != nil {
+		t.Fatal(err)
+	}
+	b := buf.Bytes()
+	r, err := regffs.New(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if broken == "" {
+		return r
+	}
+	it := r.Cells()
+	for it.Next() {
+		if vk, ok := it.Cell().Data.(*regffs.SubKeyListVk); ok && string(vk.ValueName()) == broken {
+			// the data offset follows the cell size, signature, name length
+			// and data size
+			binary.LittleEndian.PutUint32(b[0x1000+it.Cell().Offset+12:], 0x7fff0000)
+		}
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	r, err = regffs.New(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func stringValue(s string) *fstest.MapFile {
+	return &fstest.MapFile{Data: regffs.StringValue(s).Data, Sys: regffs.DataTypeEnum.RegSz}
+}
+
+// checkEntries compares the entries without their key last written times,
+// which are the build times of the keys.
+func checkEntries(t *testing.T, got, want []*Entry) {
+	t.Helper()
+	for _, entry := range got {
+		entry.KeyModTime = time.Time{}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Autoruns() =")
+		for _, entry := range got {
+			t.Errorf("\t%+v", entry)
+		}
+		t.Errorf("want")
+		for _, entry := range want {
+			t.Errorf("\t%+v", entry)
+		}
+	}
+}
+
+func TestAutorunsSystem(t *testing.T) {
+	system := buildHive(t, fstest.MapFS{
+		"Select/Current":                                    {Data: regffs.DwordValue(1).Data, Sys: regffs.DataTypeEnum.RegDword},
+		"ControlSet001/Services/Tcpip/ImagePath":            stringValue(`System32\drivers\tcpip.sys`),
+		"ControlSet001/Services/evil/ImagePath":             stringValue(`C:\Users\Public\evil.exe`),
+		"ControlSet001/Services/evil/Parameters/ServiceDll": stringValue(`C:\Users\Public\evil.dll`),
+		"ControlSet001/Services/empty/ImagePath":            stringValue(""),
+		"ControlSet002/Services/other/ImagePath":            stringValue(`C:\other.exe`),
+		"ControlSet001/Control/Session Manager/BootExecute": {Data: regffs.MultiStringValue("autocheck autochk *", "evil").Data, Sys: regffs.DataTypeEnum.RegMultiSz},
+	}, "")
+	entries, err := Autoruns(system)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkEntries(t, entries, []*Entry{
+		{Category: "Services", Key: "ControlSet001/Services/Tcpip", Value: "ImagePath", Command: `System32\drivers\tcpip.sys`},
+		{Category: "Services", Key: "ControlSet001/Services/evil", Value: "ImagePath", Command: `C:\Users\Public\evil.exe`},
+		{Category: "Services", Key: "ControlSet001/Services/evil/Parameters", Value: "ServiceDll", Command: `C:\Users\Public\evil.dll`},
+		{Category: "Session Manager", Key: "ControlSet001/Control/Session Manager", Value: "BootExecute", Command: "autocheck autochk *"},
+		{Category: "Session Manager", Key: "ControlSet001/Control/Session Manager", Value: "BootExecute", Command: "evil"},
+	})
+}
+
+func TestAutorunsSoftware(t *testing.T) {
+	const (
+		run = "Microsoft/Windows/CurrentVersion/Run"
+		bho = "Microsoft/Windows/CurrentVersion/Explorer/Browser Helper Objects/{1f2e3d4c-0000-4000-8000-000000000001}"
+	)
+	software := buildHive(t, fstest.MapFS{
+		run + "/Updater": stringValue(`"C:\Program Files\Updater\updater.exe" /silent`),
+		run + "/Broken":  stringValue(`C:\broken.exe`),
+		"Microsoft/Windows/CurrentVersion/RunOnce/Setup":           stringValue(`C:\setup.exe`),
+		"Microsoft/Windows NT/CurrentVersion/Winlogon/Shell":       stringValue("explorer.exe, evil.exe"),
+		"Microsoft/Windows NT/CurrentVersion/Winlogon/Userinit":    stringValue(`C:\Windows\system32\userinit.exe,`),
+		"Microsoft/Windows NT/CurrentVersion/Winlogon/DefaultUser": stringValue("joe"),
+		bho + "/(default)": stringValue("Evil Toolbar"),
+	}, "Broken")
+	entries, err := Autoruns(software)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkEntries(t, entries, []*Entry{
+		{Category: "Run", Key: run, Value: "Updater", Command: `"C:\Program Files\Updater\updater.exe" /silent`},
+		{Category: "Run", Key: "Microsoft/Windows/CurrentVersion/RunOnce", Value: "Setup", Command: `C:\setup.exe`},
+		{Category: "Winlogon", Key: "Microsoft/Windows NT/CurrentVersion/Winlogon", Value: "Shell", Command: "explorer.exe, evil.exe"},
+		{Category: "Winlogon", Key: "Microsoft/Windows NT/CurrentVersion/Winlogon", Value: "Userinit", Command: `C:\Windows\system32\userinit.exe,`},
+		{Category: "Explorer", Key: bho, Value: "{1f2e3d4c-0000-4000-8000-000000000001}", Command: "Evil Toolbar"},
+	})
+}
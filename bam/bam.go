@@ -0,0 +1,180 @@
+// Package bam decodes the execution evidence of the Background Activity
+// Moderator (BAM) and Desktop Activity Moderator (DAM) services of SYSTEM
+// hives opened with regffs.
+package bam
+
+import (
+	"encoding/binary"
+	"errors"
+	"io/fs"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/forensicanalysis/regffs"
+	"github.com/forensicanalysis/regffs/artifacts"
+	"github.com/forensicanalysis/regffs/sam"
+)
+
+// userSettingsKeys are relative to the current control set. Windows 10
+// 1809 moved the UserSettings key into a State subkey.
+var userSettingsKeys = []struct {
+	service string
+	key     string
+}{
+	{"bam", "Services/bam/State/UserSettings"},
+	{"bam", "Services/bam/UserSettings"},
+	{"dam", "Services/dam/State/UserSettings"},
+	{"dam", "Services/dam/UserSettings"},
+}
+
+const profileListKey = "Microsoft/Windows NT/CurrentVersion/ProfileList"
+
+// Entry is the last execution of a program by a user.
+type Entry struct {
+	// Service is either bam or dam.
+	Service string `json:"service"`
+	SID     string `json:"sid"`
+	// User is only set if the SID could be resolved.
+	User string `json:"user,omitempty"`
+	// Path is the executable path in NT device notation, e.g.
+	// \Device\HarddiskVolume2\Windows\System32\cmd.exe, or an application
+	// ID.
+	Path         string    `json:"path"`
+	LastExecuted time.Time `json:"last_executed"`
+	// KeyModTime is the last written time of the SID key.
+	KeyModTime time.Time `json:"key_mtime"`
+}
+
+// Entries parses the BAM and DAM entries of a SYSTEM hive. The SAM and
+// SOFTWARE hives are optional and used to resolve SIDs to user names.
+func Entries(system, samHive, software *regffs.Regffs) ([]*Entry, error) {
+	controlSet, err := artifacts.CurrentControlSet(system)
+	if err != nil {
+		return nil, err
+	}
+
+	names, err := UserNames(samHive, software)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []*Entry
+	for _, userSettings := range userSettingsKeys {
+		key := path.Join(controlSet, userSettings.key)
+		sids, err := fs.ReadDir(system, key)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, sid := range sids {
+			if !sid.IsDir() {
+				continue
+			}
+			sidEntries, err := readSID(system, path.Join(key, sid.Name()))
+			if err != nil {
+				return nil, err
+			}
+			for _, entry := range sidEntries {
+				entry.Service = userSettings.service
+				entry.User = names[entry.SID]
+			}
+			entries = append(entries, sidEntries...)
+		}
+	}
+	return entries, nil
+}
+
+func readSID(r *regffs.Regffs, key string) ([]*Entry, error) {
+	info, err := fs.Stat(r, key)
+	if err != nil {
+		return nil, err
+	}
+	values, err := fs.ReadDir(r, key)
+	if err != nil {
+		return nil, err
+	}
+	var entries []*Entry
+	for _, value := range values {
+		f, ok := value.(*regffs.File)
+		if !ok || f.IsDir() {
+			continue
+		}
+		v, err := f.Value()
+		if err != nil {
+			return nil, err
+		}
+		lastExecuted, ok := parseValue(v)
+		if !ok {
+			continue
+		}
+		entries = append(entries, &Entry{
+			SID:          path.Base(key),
+			Path:         f.Name(),
+			LastExecuted: lastExecuted,
+			KeyModTime:   info.ModTime(),
+		})
+	}
+	return entries, nil
+}
+
+// parseValue decodes the last execution time of an entry value, which
+// starts with a FILETIME. The Version and SequenceNumber values of the key
+// are DWORDs and skipped.
+func parseValue(v *regffs.Value) (time.Time, bool) {
+	if v.Type != regffs.DataTypeEnum.RegBinary || len(v.Data) < 8 {
+		return time.Time{}, false
+	}
+	return regffs.FiletimeToTime(binary.LittleEndian.Uint64(v.Data)), true
+}
+
+// UserNames maps SIDs to user names using the local accounts of a SAM hive
+// and the profile paths of the ProfileList key of a SOFTWARE hive. Both
+// hives are optional, SAM names take precedence.
+func UserNames(samHive, software *regffs.Regffs) (map[string]string, error) {
+	names := map[string]string{}
+	if software != nil {
+		profiles, err := fs.ReadDir(software, profileListKey)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+		for _, profile := range profiles {
+			if !profile.IsDir() {
+				continue
+			}
+			v, err := software.Value(path.Join(profileListKey, profile.Name(), "ProfileImagePath"))
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			if name := profileName(v.String()); name != "" {
+				names[profile.Name()] = name
+			}
+		}
+	}
+	if samHive != nil {
+		domainSID, err := sam.DomainSID(samHive)
+		if err != nil {
+			return nil, err
+		}
+		users, err := sam.Users(samHive)
+		if err != nil {
+			return nil, err
+		}
+		for _, user := range users {
+			names[user.SID(domainSID)] = user.Name
+		}
+	}
+	return names, nil
+}
+
+// profileName returns the last element of a profile path like
+// %SystemDrive%\Users\joe.
+func profileName(profilePath string) string {
+	profilePath = strings.TrimRight(profilePath, `\`)
+	return profilePath[strings.LastIndexByte(profilePath, '\\')+1:]
+}
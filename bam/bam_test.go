@@ -0,0 +1,60 @@
+package bam
+
+import (
+	"encoding/binary"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/forensicanalysis/regffs"
+)
+
+func TestParseValue(t *testing.T) {
+	lastExecuted := time.Date(2019, 3, 4, 5, 6, 7, 0, time.UTC)
+	data := binary.LittleEndian.AppendUint64(nil, uint64(lastExecuted.Unix())*1e7+116444736000000000)
+	data = append(data, make([]byte, 16)...)
+
+	tests := []struct {
+		name  string
+		value *regffs.Value
+		want  time.Time
+		ok    bool
+	}{
+		{"entry", &regffs.Value{Type: regffs.DataTypeEnum.RegBinary, Data: data}, lastExecuted, true},
+		{"version", &regffs.Value{Type: regffs.DataTypeEnum.RegDword, Data: []byte{1, 0, 0, 0}}, time.Time{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseValue(tt.value)
+			if ok != tt.ok || !got.Equal(tt.want) {
+				t.Errorf("parseValue() = %s, %v, want %s, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestUserNames(t *testing.T) {
+	f, err := os.Open("../testdata/SAM")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	samHive, err := regffs.New(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names, err := UserNames(samHive, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := names["S-1-5-21-1760460187-1592185332-161725925-1000"]; got != "Preston" {
+		t.Errorf("UserNames()[...-1000] = %q, want Preston", got)
+	}
+}
+
+func TestProfileName(t *testing.T) {
+	if got := profileName(`%SystemDrive%\Users\joe\`); got != "joe" {
+		t.Errorf("profileName() = %q, want joe", got)
+	}
+}
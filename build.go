@@ -0,0 +1,81 @@
+package regffs
+
+import (
+	"io"
+	"io/fs"
+	"strings"
+	"time"
+)
+
+// Build writes a hive with the keys and values of fsys to w, the inverse of
+// New. Directories become keys and files become values, a file named
+// "(default)" is the default value of its key. The data type of a value is
+// taken from its file info: regffs values keep their type, and Sys() may
+// return a data type like DataTypeEnum.RegSz, e.g. as fstest.MapFile.Sys.
+// Other files, like those of an OS directory, are stored as REG_BINARY.
+// The modification times of directories are used as key last written
+// times.
+func Build(w io.Writer, fsys fs.FS) error {
+	h, err := NewHive()
+	if err != nil {
+		return err
+	}
+
+	// keys are tracked by their walked path, as key and value names may
+	// contain slashes
+	keys := map[string]uint32{".": h.rootKey()}
+	modTimes := map[uint32]time.Time{}
+	err = fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		parent, ok := keys[parentName(name, d.Name())]
+		if name != "." && !ok {
+			return &fs.PathError{Op: "build", Path: name, Err: fs.ErrNotExist}
+		}
+
+		if d.IsDir() {
+			nk := h.rootKey()
+			if name != "." {
+				if nk, err = h.createKey(parent, d.Name()); err != nil {
+					return &fs.PathError{Op: "build", Path: name, Err: err}
+				}
+				keys[name] = nk
+			}
+			if info, err := d.Info(); err == nil && !info.ModTime().IsZero() {
+				modTimes[nk] = info.ModTime()
+			}
+			return nil
+		}
+
+		value, err := readValue(fsys, name, d)
+		if err != nil {
+			return err
+		}
+		value.Name = d.Name()
+		if err := h.setValue(parent, value); err != nil {
+			return &fs.PathError{Op: "build", Path: name, Err: err}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// last written times are set after all keys are complete, as adding
+	// subkeys and values updates them
+	for nk, modTime := range modTimes {
+		h.put64(nk, nkLastWritten, TimeToFiletime(modTime))
+	}
+	_, err = h.WriteTo(w)
+	return err
+}
+
+// parentName returns the walked path of the parent of the entry name at
+// walked path p.
+func parentName(p, name string) string {
+	if parent := strings.TrimSuffix(p, "/"+name); parent != p {
+		return parent
+	}
+	return "."
+}
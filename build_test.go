@@ -0,0 +1,175 @@
+package regffs
+
+import (
+	"bytes"
+	"io/fs"
+	"os"
+	"reflect"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestBuild(t *testing.T) {
+	modTime := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	fsys := fstest.MapFS{
+		"Software/Run":           {Mode: 0o755 | os.ModeDir, ModTime: modTime},
+		"Software/Run/Updater":   {Data: []byte("u\x00p\x00\x00\x00"), Sys: DataTypeEnum.RegSz},
+		"Software/Run/(default)": {Data: []byte{1, 0, 0, 0}, Sys: DataTypeEnum.RegDword},
+		"Software/Raw":           {Data: []byte{1, 2, 3, 4, 5, 6}},
+		"Empty":                  {Mode: 0o755 | os.ModeDir},
+	}
+
+	var buf bytes.Buffer
+	if err := Build(&buf, fsys); err != nil {
+		t.Fatal(err)
+	}
+	r, err := New(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []*Value{
+		{Name: "Software/Run/Updater", Type: DataTypeEnum.RegSz, Data: []byte("u\x00p\x00\x00\x00")},
+		{Name: "Software/Run/(default)", Type: DataTypeEnum.RegDword, Data: []byte{1, 0, 0, 0}},
+		{Name: "Software/Raw", Type: DataTypeEnum.RegBinary, Data: []byte{1, 2, 3, 4, 5, 6}},
+	}
+	for _, want := range expected {
+		got, err := r.Value(want.Name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Type != want.Type || !bytes.Equal(got.Data, want.Data) {
+			t.Errorf("Value(%s) = %d %x, want %d %x", want.Name, got.Type, got.Data, want.Type, want.Data)
+		}
+	}
+	info, err := fs.Stat(r, "Software/Run")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.ModTime().Equal(modTime) {
+		t.Errorf("ModTime() = %v, want %v", info.ModTime(), modTime)
+	}
+	if _, err := fs.Stat(r, "Empty"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestBuildRoundTrip(t *testing.T) {
+	f, err := os.Open("testdata/NTUSER.DAT")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	src, err := New(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := Build(&buf, src); err != nil {
+		t.Fatal(err)
+	}
+	dst, err := New(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes, err := Diff(src, dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, change := range changes {
+		t.Error(change)
+	}
+}
+
+func TestBuildUTF16Names(t *testing.T) {
+	h, err := NewHive()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := h.CreateKey("дом/Latin-ü"); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.SetValue("дом", &Value{Name: "имя", Type: DataTypeEnum.RegDword, Data: []byte{1, 0, 0, 0}}); err != nil {
+		t.Fatal(err)
+	}
+	src, err := h.Regffs()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := Build(&buf, src); err != nil {
+		t.Fatal(err)
+	}
+	checkNames(t, buf.Bytes())
+}
+
+// checkNames checks the names and compressed flags of the keys and values
+// created by TestBuildUTF16Names and TestCompactUTF16Names.
+func checkNames(t *testing.T, hive []byte) {
+	t.Helper()
+	dst, err := OpenHive(bytes.NewReader(hive))
+	if err != nil {
+		t.Fatal(err)
+	}
+	nk, err := dst.lookup("open", "дом")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if flags := dst.get16(nk, nkFlags); flags&NkFlags.KeyCompName != 0 {
+		t.Errorf("дом flags = 0x%x, want UTF-16 name", flags)
+	}
+	i, ok := dst.findValue(nk, "имя")
+	if !ok {
+		t.Fatal("value имя not found")
+	}
+	if flags := dst.get16(dst.values(nk)[i], vkFlags); flags&vkCompName != 0 {
+		t.Errorf("имя flags = 0x%x, want UTF-16 name", flags)
+	}
+	latin1, err := dst.lookup("open", "дом/Latin-ü")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if flags := dst.get16(latin1, nkFlags); flags&NkFlags.KeyCompName == 0 {
+		t.Errorf("Latin-ü flags = 0x%x, want compressed name", flags)
+	}
+
+	r, err := New(bytes.NewReader(hive))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fs.Stat(r, "дом/Latin-ü"); err != nil {
+		t.Error(err)
+	}
+	entries, err := fs.ReadDir(r, "дом")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	if want := []string{"Latin-ü", "имя"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("ReadDir(дом) = %q, want %q", names, want)
+	}
+}
+
+func TestBuildNonLatin1Names(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Build(&buf, fstest.MapFS{"Key日本/Wert名前": {Data: []byte{1}}}); err != nil {
+		t.Fatal(err)
+	}
+	r, err := New(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := fs.Stat(r, "Key日本/Wert名前")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Name() != "Wert名前" {
+		t.Errorf("Name() = %q, want Wert名前", info.Name())
+	}
+}
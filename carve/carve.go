@@ -0,0 +1,224 @@
+// Package carve recovers regf hives and hive bins from raw data like disk
+// images, unallocated space or memory dumps.
+package carve
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/forensicanalysis/regffs"
+)
+
+const (
+	// SectorSize is the alignment at which signatures are searched.
+	SectorSize = 512
+
+	baseBlockSize    = 0x1000
+	hiveBinAlignment = 0x1000
+	hiveBinHeaderLen = 0x20
+	checksumOffset   = 0x1fc
+	maxHiveBinSize   = 0x7ffff000
+
+	scanBufferSize = 1 << 20
+)
+
+// Hive is a carved hive, a base block followed by contiguous hive bins, or
+// contiguous hive bins without base block.
+type Hive struct {
+	// Offset of the base block, or of the first hive bin if Header is nil.
+	Offset int64 `json:"offset"`
+	// Header is nil if no base block precedes the hive bins.
+	Header *regffs.FileHeader `json:"-"`
+	// ValidChecksum reports whether the checksum of the base block is
+	// correct.
+	ValidChecksum bool  `json:"valid_checksum"`
+	Bins          []Bin `json:"bins"`
+
+	r io.ReaderAt
+}
+
+// Bin is a carved hive bin.
+type Bin struct {
+	// Offset in the scanned data.
+	Offset int64 `json:"offset"`
+	// HiveOffset is the offset of the bin relative to the start of the hive
+	// bins data, as stored in the bin header.
+	HiveOffset uint32 `json:"hive_offset"`
+	Size       uint32 `json:"size"`
+}
+
+// Complete reports whether the hive has a base block and all hive bins it
+// announces.
+func (h *Hive) Complete() bool {
+	return h.Header != nil && h.binsSize() == h.Header.HiveBinsDataSize() && (len(h.Bins) == 0 || h.Bins[0].HiveOffset == 0)
+}
+
+// binsSize is the size of the hive bins data up to the end of the last bin.
+func (h *Hive) binsSize() uint32 {
+	if len(h.Bins) == 0 {
+		return 0
+	}
+	last := h.Bins[len(h.Bins)-1]
+	return last.HiveOffset + last.Size
+}
+
+// Carve scans size bytes of r for regf base blocks and hive bins at sector
+// boundaries and groups them into hives. Hive bins that follow a base block
+// or another bin directly, both in r and by their stored offsets, belong to
+// the same hive.
+func Carve(r io.ReaderAt, size int64) ([]*Hive, error) {
+	var hives []*Hive
+	var current *Hive
+	// next is the offset in r at which the next bin of current is expected
+	var next int64 = -1
+
+	buf := make([]byte, scanBufferSize)
+	for chunk := int64(0); chunk < size; chunk += scanBufferSize {
+		n, err := r.ReadAt(buf, chunk)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+		if chunk+int64(n) > size {
+			n = int(size - chunk)
+		}
+		for i := 0; i+4 <= n; i += SectorSize {
+			offset := chunk + int64(i)
+			if offset < next {
+				continue
+			}
+			switch string(buf[i : i+4]) {
+			case "regf":
+				header, ok := decodeHeader(r, offset)
+				if !ok {
+					continue
+				}
+				current = &Hive{Offset: offset, Header: header, ValidChecksum: validChecksum(r, offset), r: r}
+				hives = append(hives, current)
+				next = offset + baseBlockSize
+			case "hbin":
+				bin, ok := decodeBin(r, offset)
+				if !ok {
+					continue
+				}
+				if current == nil || offset != next || bin.HiveOffset != current.binsSize() {
+					current = &Hive{Offset: offset, r: r}
+					hives = append(hives, current)
+				}
+				current.Bins = append(current.Bins, bin)
+				next = offset + int64(bin.Size)
+			}
+		}
+	}
+	return hives, nil
+}
+
+func decodeHeader(r io.ReaderAt, offset int64) (*regffs.FileHeader, bool) {
+	header := &regffs.FileHeader{}
+	regf := &regffs.Regf{}
+	if err := header.Decode(io.NewSectionReader(r, offset, baseBlockSize), regf, regf); err != nil {
+		return nil, false
+	}
+	if header.MajorVersion() != 1 || header.HiveBinsDataSize()%hiveBinAlignment != 0 {
+		return nil, false
+	}
+	return header, true
+}
+
+// validChecksum verifies the XOR checksum of the base block at offset.
+func validChecksum(r io.ReaderAt, offset int64) bool {
+	b := make([]byte, checksumOffset+4)
+	if _, err := r.ReadAt(b, offset); err != nil {
+		return false
+	}
+	return regffs.HeaderChecksum(b) == binary.LittleEndian.Uint32(b[checksumOffset:])
+}
+
+func decodeBin(r io.ReaderAt, offset int64) (Bin, bool) {
+	header := &regffs.HiveBinHeader{}
+	regf := &regffs.Regf{}
+	if err := header.Decode(io.NewSectionReader(r, offset, hiveBinHeaderLen), regf, regf); err != nil {
+		return Bin{}, false
+	}
+	size, hiveOffset := header.Size(), header.Offset()
+	if size == 0 || size%hiveBinAlignment != 0 || size > maxHiveBinSize || hiveOffset%hiveBinAlignment != 0 {
+		return Bin{}, false
+	}
+	// the bin must be completely readable
+	if _, err := r.ReadAt(make([]byte, 1), offset+int64(size)-1); err != nil {
+		return Bin{}, false
+	}
+	return Bin{Offset: offset, HiveOffset: hiveOffset, Size: size}, true
+}
+
+// Bytes reassembles the hive. Hive bins beyond the hive bins data size of
+// the base block are skipped. Hives without base block get a new one with
+// the root key found in the bins, they must start at hive offset 0 as cells
+// are referenced by their hive offsets.
+func (h *Hive) Bytes() ([]byte, error) {
+	size := h.binsSize()
+	if h.Header != nil && size > h.Header.HiveBinsDataSize() {
+		size = h.Header.HiveBinsDataSize()
+	}
+	if h.Header == nil && len(h.Bins) > 0 && h.Bins[0].HiveOffset != 0 {
+		return nil, fmt.Errorf("hive bins without base block start at hive offset 0x%x", h.Bins[0].HiveOffset)
+	}
+	data := make([]byte, baseBlockSize+int(size))
+	if h.Header != nil {
+		if _, err := h.r.ReadAt(data[:baseBlockSize], h.Offset); err != nil {
+			return nil, err
+		}
+	}
+	for _, bin := range h.Bins {
+		if bin.HiveOffset+bin.Size > size {
+			break
+		}
+		start := baseBlockSize + int(bin.HiveOffset)
+		if _, err := h.r.ReadAt(data[start:start+int(bin.Size)], bin.Offset); err != nil {
+			return nil, err
+		}
+	}
+	if h.Header == nil {
+		root, err := regffs.FindRootKey(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		writeBaseBlock(data, root)
+	}
+	return data, nil
+}
+
+// WriteTo writes the reassembled hive to w.
+func (h *Hive) WriteTo(w io.Writer) (int64, error) {
+	data, err := h.Bytes()
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(data)
+	return int64(n), err
+}
+
+// Open opens the reassembled hive.
+func (h *Hive) Open() (*regffs.Regffs, error) {
+	data, err := h.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	return regffs.New(bytes.NewReader(data))
+}
+
+// writeBaseBlock writes a base block of a version 1.5 hive.
+func writeBaseBlock(data []byte, root uint32) {
+	copy(data, "regf")
+	binary.LittleEndian.PutUint32(data[0x04:], 1)
+	binary.LittleEndian.PutUint32(data[0x08:], 1)
+	binary.LittleEndian.PutUint32(data[0x14:], 1)
+	binary.LittleEndian.PutUint32(data[0x18:], 5)
+	binary.LittleEndian.PutUint32(data[0x20:], 1)
+	binary.LittleEndian.PutUint32(data[0x24:], root)
+	binary.LittleEndian.PutUint32(data[0x28:], uint32(len(data)-baseBlockSize))
+	binary.LittleEndian.PutUint32(data[0x2c:], 1)
+	binary.LittleEndian.PutUint32(data[checksumOffset:], regffs.HeaderChecksum(data))
+}
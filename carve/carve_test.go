@@ -0,0 +1,124 @@
+package carve
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/fs"
+	"os"
+	"testing"
+	"testing/fstest"
+
+	"github.com/forensicanalysis/regffs"
+)
+
+func TestCarve(t *testing.T) {
+	ntuser, err := os.ReadFile("../testdata/NTUSER.DAT")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var built bytes.Buffer
+	if err := regffs.Build(&built, fstest.MapFS{"Carved/Key": {Data: []byte{1, 2, 3}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// image with a complete hive, the hive bins of a hive without base
+	// block and junk with signatures in between
+	var image bytes.Buffer
+	image.Write(bytes.Repeat([]byte{0xaa}, 3*SectorSize))
+	ntuserOffset := int64(image.Len())
+	image.Write(ntuser)
+	image.Write([]byte("hbin"))
+	image.Write(bytes.Repeat([]byte{0}, SectorSize-4))
+	binsOffset := int64(image.Len())
+	image.Write(built.Bytes()[baseBlockSize:])
+	image.Write([]byte("regf"))
+	image.Write(bytes.Repeat([]byte{0}, SectorSize-4))
+
+	hives, err := Carve(bytes.NewReader(image.Bytes()), int64(image.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hives) != 2 {
+		t.Fatalf("Carve() = %d hives, want 2", len(hives))
+	}
+
+	complete := hives[0]
+	if complete.Offset != ntuserOffset || complete.Header == nil || !complete.Complete() || !complete.ValidChecksum {
+		t.Errorf("hive 0 = %+v, want complete hive at %d", complete, ntuserOffset)
+	}
+	data, err := complete.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the file is padded after the last hive bin
+	if !bytes.Equal(data, ntuser[:len(data)]) {
+		t.Error("reassembled hive differs")
+	}
+
+	headerless := hives[1]
+	if headerless.Offset != binsOffset || headerless.Header != nil || headerless.Complete() {
+		t.Errorf("hive 1 = %+v, want hive bins at %d", headerless, binsOffset)
+	}
+	r, err := headerless.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := r.Value("Carved/Key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(v.Data, []byte{1, 2, 3}) {
+		t.Errorf("Value() = %x, want 010203", v.Data)
+	}
+	if _, err := fs.Stat(r, "Carved"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCarveBinOffset(t *testing.T) {
+	var built bytes.Buffer
+	if err := regffs.Build(&built, fstest.MapFS{"Key": {Data: []byte{1}}}); err != nil {
+		t.Fatal(err)
+	}
+	// a single hive bin claiming to be at the end of a 2 GB hive
+	bin := built.Bytes()[baseBlockSize : baseBlockSize+hiveBinAlignment]
+	binary.LittleEndian.PutUint32(bin[4:], 0x7ff00000)
+
+	hives, err := Carve(bytes.NewReader(bin), int64(len(bin)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hives) != 1 {
+		t.Fatalf("Carve() = %d hives, want 1", len(hives))
+	}
+	if _, err := hives[0].Bytes(); err == nil {
+		t.Error("Bytes() of hive bins without base block not at hive offset 0 succeeded")
+	}
+}
+
+func TestCarveAnnouncedSize(t *testing.T) {
+	var built bytes.Buffer
+	if err := regffs.Build(&built, fstest.MapFS{"Key": {Data: []byte{1}}}); err != nil {
+		t.Fatal(err)
+	}
+	// a hive followed by a hive bin beyond its hive bins data size
+	hive := built.Bytes()
+	bin := append([]byte(nil), hive[baseBlockSize:baseBlockSize+hiveBinAlignment]...)
+	binary.LittleEndian.PutUint32(bin[4:], uint32(len(hive)-baseBlockSize))
+	image := append(append([]byte(nil), hive...), bin...)
+
+	hives, err := Carve(bytes.NewReader(image), int64(len(image)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hives) != 1 || len(hives[0].Bins) != 2 {
+		t.Fatalf("Carve() = %+v, want 1 hive with 2 bins", hives)
+	}
+	data, err := hives[0].Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, hive) {
+		t.Errorf("Bytes() = %d bytes, want the %d bytes of the hive", len(data), len(hive))
+	}
+}
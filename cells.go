@@ -0,0 +1,169 @@
+package regffs
+
+import (
+	"bytes"
+	"errors"
+	"io"
+)
+
+// Bin is a hive bin.
+type Bin struct {
+	// Offset of the bin, relative to the start of the hive bins data.
+	Offset int64
+	Size   int64
+	Header *HiveBinHeader
+}
+
+// Cell is an allocated or unallocated cell of a hive bin.
+type Cell struct {
+	// Offset of the cell, relative to the start of the hive bins data.
+	Offset    int64
+	Size      int64
+	Allocated bool
+	// Signature are the first two bytes of the cell data, e.g. "nk". Cells
+	// of value lists, value data and class names have no signature and
+	// start with arbitrary data.
+	Signature string
+	// Data is the decoded payload of cells with a known signature:
+	// *NamedKey, *SubKeyListVk, *SubKeyListSk, *SubKeyListLhLf,
+	// *SubKeyListLi or *SubKeyListRi. It is nil for other cells.
+	Data KSYDecoder
+
+	cell *HiveBinCell
+}
+
+// CellIterator iterates over all cells, allocated or not, in all hive bins.
+// It stops at the first invalid hive bin header, a tolerant Regffs skips
+// invalid hive bins instead. The iterator shares the reader of the Regffs
+// and seeks on every call to Next, so the Regffs can be used in between.
+//
+//	it := fsys.Cells()
+//	for it.Next() {
+//		cell := it.Cell()
+//		...
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+type CellIterator struct {
+	r *Regffs
+	// next is the file offset of the next cell, binEnd the file offset of
+	// the end of the current bin.
+	next, binEnd int64
+	bin          *Bin
+	cell         *Cell
+	err          error
+	done         bool
+}
+
+// Cells returns an iterator over all hive bins and cells.
+func (r *Regffs) Cells() *CellIterator {
+	return &CellIterator{r: r, next: hiveBinsOffset, binEnd: hiveBinsOffset}
+}
+
+// Next advances to the next cell. It returns false at the end of the hive
+// bins or on error.
+func (it *CellIterator) Next() bool {
+	if it.done {
+		return false
+	}
+	for {
+		if it.next >= it.binEnd {
+			if !it.nextBin() {
+				it.done = true
+				it.cell = nil
+				return false
+			}
+			continue
+		}
+
+		if _, err := it.r.reader.Seek(it.next, io.SeekStart); err != nil {
+			return it.fail(err)
+		}
+		cell := &HiveBinCell{}
+		err := cell.Decode(it.r.reader, it.r.regf, it.r.regf)
+		if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+			return it.fail(err)
+		}
+		size := cell.CellSize()
+		if size < 8 || it.next+size > it.binEnd {
+			it.r.warnings.add(it.next, "cell: invalid size 0x%x", size)
+			it.next = it.binEnd
+			continue
+		}
+		it.cell = &Cell{
+			Offset:    it.next - hiveBinsOffset,
+			Size:      size,
+			Allocated: cell.IsAllocated(),
+			Signature: string(cell.Identifier()),
+			Data:      cell.Data(),
+			cell:      cell,
+		}
+		it.next += size
+		return true
+	}
+}
+
+// nextBin reads the header of the hive bin at the end of the current bin.
+func (it *CellIterator) nextBin() bool {
+	binOffset := it.binEnd
+	for {
+		if _, err := it.r.reader.Seek(binOffset, io.SeekStart); err != nil {
+			return it.fail(err)
+		}
+		header := &HiveBinHeader{}
+		err := header.Decode(it.r.reader, it.r.regf, it.r.regf)
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return false
+		}
+		if err != nil {
+			return it.fail(err)
+		}
+		if !bytes.Equal(header.Signature(), []byte("hbin")) || header.Size() < hiveBinAlignment || header.Size()%hiveBinAlignment != 0 {
+			if !it.r.tolerantBin(binOffset, header) {
+				return false
+			}
+			binOffset += hiveBinAlignment
+			continue
+		}
+		it.bin = &Bin{Offset: binOffset - hiveBinsOffset, Size: int64(header.Size()), Header: header}
+		it.next = binOffset + hiveBinHeaderLen
+		it.binEnd = binOffset + int64(header.Size())
+		return true
+	}
+}
+
+func (it *CellIterator) fail(err error) bool {
+	it.err = err
+	it.done = true
+	it.cell = nil
+	return false
+}
+
+// Cell returns the current cell.
+func (it *CellIterator) Cell() *Cell {
+	return it.cell
+}
+
+// Bin returns the hive bin of the current cell.
+func (it *CellIterator) Bin() *Bin {
+	return it.bin
+}
+
+// Err returns the first error that stopped the iteration.
+func (it *CellIterator) Err() error {
+	return it.err
+}
+
+// Bytes reads the data of the current cell without the size field.
+func (it *CellIterator) Bytes() ([]byte, error) {
+	if it.cell == nil {
+		return nil, errors.New("no cell")
+	}
+	if _, err := it.r.reader.Seek(it.cell.Offset+hiveBinsOffset+4, io.SeekStart); err != nil {
+		return nil, err
+	}
+	data := make([]byte, it.cell.Size-4)
+	_, err := io.ReadFull(it.r.reader, data)
+	return data, err
+}
@@ -0,0 +1,62 @@
+package regffs
+
+import (
+	"os"
+	"testing"
+)
+
+func TestCells(t *testing.T) {
+	f, err := os.Open("testdata/NTUSER.DAT")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	fsys, err := New(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var bins, cells int
+	var binSize, binUsed int64
+	var last *Bin
+	it := fsys.Cells()
+	for it.Next() {
+		cell := it.Cell()
+		if bin := it.Bin(); bin != last {
+			if last != nil && binUsed != binSize {
+				t.Errorf("bin 0x%x: cells cover 0x%x of 0x%x bytes", last.Offset, binUsed, binSize)
+			}
+			bins++
+			last, binSize, binUsed = bin, bin.Size-hiveBinHeaderLen, 0
+		}
+		cells++
+		binUsed += cell.Size
+
+		if cell.Offset == int64(fsys.rootOffset) {
+			nk, ok := cell.Data.(*NamedKey)
+			if !ok || !cell.Allocated || cell.Signature != "nk" || nk.Flags()&NkFlags.KeyHiveEntry == 0 {
+				t.Errorf("root key cell = %+v", cell)
+			}
+			data, err := it.Bytes()
+			if err != nil || string(data[:2]) != "nk" || int64(len(data)) != cell.Size-4 {
+				t.Errorf("Bytes() = %q, %v", data, err)
+			}
+			// the iterator must not depend on the reader position
+			if _, err := fsys.Open("AppEvents/Schemes"); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if binUsed != binSize {
+		t.Errorf("bin 0x%x: cells cover 0x%x of 0x%x bytes", last.Offset, binUsed, binSize)
+	}
+	if it.Next() {
+		t.Error("Next() after the end = true")
+	}
+	if last.Offset+last.Size != int64(fsys.header.HiveBinsDataSize()) || bins != 151 || cells < 1000 {
+		t.Errorf("Cells() = %d bins, %d cells, end 0x%x", bins, cells, last.Offset+last.Size)
+	}
+}
@@ -0,0 +1,37 @@
+package regffs
+
+import (
+	"fmt"
+	"io/fs"
+)
+
+// noClassName marks keys without a class name.
+const noClassName = 0xffffffff
+
+// ClassName reads the class name of the key at name.
+func (r *Regffs) ClassName(name string) (string, error) {
+	f, err := r.Open(name)
+	if err != nil {
+		return "", err
+	}
+	return f.(*File).ClassName()
+}
+
+// ClassName reads the class name of the key f. Class names are rarely used,
+// but some keys like the Lsa subkeys of the SYSTEM hive store data in them.
+// Keys without class name return an empty string.
+func (f *File) ClassName() (string, error) {
+	nk, ok := f.cell.Data().(*NamedKey)
+	if !ok {
+		return "", &fs.PathError{Op: "read", Path: f.Name(), Err: fmt.Errorf("is not a key")}
+	}
+	size := nk.ClassNameLength()
+	if nk.ClassNameOffset() == noClassName || size == 0 {
+		return "", nil
+	}
+	data, err := readCellData(f.reader, int64(nk.ClassNameOffset())+0x1000, uint32(size))
+	if err != nil {
+		return "", err
+	}
+	return decodeString(data)
+}
@@ -0,0 +1,38 @@
+package regffs
+
+import (
+	"os"
+	"testing"
+)
+
+func TestClassName(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		expected string
+	}{
+		{"class name", "Software/Intel/Indeo", "Application User Data"},
+		{"no class name", "Control Panel/Desktop", ""},
+	}
+	f, err := os.Open("testdata/NTUSER.DAT")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	fsys, err := New(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			className, err := fsys.ClassName(tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if className != tt.expected {
+				t.Errorf("ClassName() = %q, want %q", className, tt.expected)
+			}
+		})
+	}
+}
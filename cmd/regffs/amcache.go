@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/spf13/cobra"
+
+	"github.com/forensicanalysis/regffs/amcache"
+)
+
+func amcacheCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:           "amcache [Amcache.hve]",
+		Short:         "decode Amcache file entries",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		Args:          cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			fsys, closeHive, err := openHive(args[0])
+			if err != nil {
+				return err
+			}
+			defer closeHive()
+
+			schema, entries, err := amcache.Entries(fsys)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("Schema: %s\n\n", schema)
+			var rows [][]string
+			for _, entry := range entries {
+				rows = append(rows, []string{
+					formatTime(entry.KeyModTime),
+					entry.SHA1,
+					strconv.FormatUint(entry.Size, 10),
+					entry.Publisher,
+					entry.Path,
+				})
+			}
+			return printTable([]string{"KEY MODIFIED", "SHA1", "SIZE", "PUBLISHER", "PATH"}, rows)
+		},
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/spf13/cobra"
+
+	"github.com/forensicanalysis/regffs/artifacts"
+)
+
+func appCompatCacheCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:           "appcompatcache [SYSTEM]",
+		Short:         "decode the AppCompatCache (ShimCache)",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		Args:          cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			fsys, closeHive, err := openHive(args[0])
+			if err != nil {
+				return err
+			}
+			defer closeHive()
+
+			format, entries, err := artifacts.AppCompatCache(fsys)
+			if err != nil {
+				return err
+			}
+
+			fmt.Println("Format:", format)
+			var rows [][]string
+			for _, entry := range entries {
+				executed := "-"
+				if entry.Executed != nil {
+					executed = strconv.FormatBool(*entry.Executed)
+				}
+				rows = append(rows, []string{
+					strconv.Itoa(entry.Position),
+					formatTime(entry.LastModified),
+					executed,
+					entry.Path,
+				})
+			}
+			return printTable([]string{"POSITION", "LAST MODIFIED", "EXECUTED", "PATH"}, rows)
+		},
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/forensicanalysis/regffs/autoruns"
+)
+
+func autorunsCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:           "autoruns [file]",
+		Short:         "list persistence and autostart entries",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		Args:          cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			fsys, closeHive, err := openHive(args[0])
+			if err != nil {
+				return err
+			}
+			defer closeHive()
+
+			entries, err := autoruns.Autoruns(fsys)
+			if err != nil {
+				return err
+			}
+
+			var rows [][]string
+			for _, entry := range entries {
+				rows = append(rows, []string{
+					formatTime(entry.KeyModTime),
+					entry.Category,
+					entry.Key + "/" + entry.Value,
+					entry.Command,
+				})
+			}
+			return printTable([]string{"KEY MODIFIED", "CATEGORY", "LOCATION", "COMMAND"}, rows)
+		},
+	}
+}
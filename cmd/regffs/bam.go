@@ -0,0 +1,60 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/forensicanalysis/regffs"
+	"github.com/forensicanalysis/regffs/bam"
+)
+
+func bamCmd() *cobra.Command {
+	var samFile, softwareFile string
+	cmd := &cobra.Command{
+		Use:           "bam [SYSTEM]",
+		Short:         "decode BAM and DAM execution entries",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		Args:          cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			system, closeSystem, err := openHive(args[0])
+			if err != nil {
+				return err
+			}
+			defer closeSystem()
+
+			var samHive, software *regffs.Regffs
+			for _, optional := range []struct {
+				name string
+				hive **regffs.Regffs
+			}{{samFile, &samHive}, {softwareFile, &software}} {
+				if optional.name == "" {
+					continue
+				}
+				fsys, closeHive, err := openHive(optional.name)
+				if err != nil {
+					return err
+				}
+				defer closeHive()
+				*optional.hive = fsys
+			}
+
+			entries, err := bam.Entries(system, samHive, software)
+			if err != nil {
+				return err
+			}
+
+			var rows [][]string
+			for _, entry := range entries {
+				user := entry.User
+				if user == "" {
+					user = entry.SID
+				}
+				rows = append(rows, []string{formatTime(entry.LastExecuted), entry.Service, user, entry.Path})
+			}
+			return printTable([]string{"LAST EXECUTED", "SERVICE", "USER", "PATH"}, rows)
+		},
+	}
+	cmd.Flags().StringVar(&samFile, "sam", "", "SAM hive to resolve local user names")
+	cmd.Flags().StringVar(&softwareFile, "software", "", "SOFTWARE hive to resolve user names from profiles")
+	return cmd
+}
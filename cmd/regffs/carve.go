@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/spf13/cobra"
+
+	"github.com/forensicanalysis/regffs/carve"
+)
+
+func carveCmd() *cobra.Command {
+	var output string
+	cmd := &cobra.Command{
+		Use:           "carve [image]",
+		Short:         "recover hives and hive bins from raw data",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		Args:          cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			f, err := os.Open(args[0])
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			info, err := f.Stat()
+			if err != nil {
+				return err
+			}
+
+			hives, err := carve.Carve(f, info.Size())
+			if err != nil {
+				return err
+			}
+
+			var rows [][]string
+			for _, hive := range hives {
+				var size uint64
+				for _, bin := range hive.Bins {
+					size += uint64(bin.Size)
+				}
+				file := "-"
+				if output != "" {
+					file = filepath.Join(output, fmt.Sprintf("hive_%d.dat", hive.Offset))
+					if err := writeHive(file, hive); err != nil {
+						file = err.Error()
+					}
+				}
+				rows = append(rows, []string{
+					strconv.FormatInt(hive.Offset, 10),
+					strconv.FormatBool(hive.Header != nil),
+					strconv.FormatBool(hive.Complete()),
+					strconv.Itoa(len(hive.Bins)),
+					strconv.FormatUint(size, 10),
+					file,
+				})
+			}
+			return printTable([]string{"OFFSET", "HEADER", "COMPLETE", "BINS", "BINS SIZE", "FILE"}, rows)
+		},
+	}
+	cmd.Flags().StringVarP(&output, "output", "o", "", "export the reassembled hives to this directory")
+	return cmd
+}
+
+func writeHive(name string, hive *carve.Hive) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if _, err := hive.WriteTo(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
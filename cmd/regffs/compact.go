@@ -0,0 +1,36 @@
+package main
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/forensicanalysis/regffs"
+)
+
+func compactCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:           "compact [file] [output]",
+		Short:         "rewrite a hive without free cells and deleted data",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		Args:          cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			src, closeSrc, err := openHive(args[0])
+			if err != nil {
+				return err
+			}
+			defer closeSrc()
+
+			dst, err := os.Create(args[1])
+			if err != nil {
+				return err
+			}
+			if err := regffs.Compact(dst, src); err != nil {
+				dst.Close()
+				return err
+			}
+			return dst.Close()
+		},
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/forensicanalysis/regffs"
+)
+
+func diffCmd() *cobra.Command {
+	var jsonOutput bool
+	cmd := &cobra.Command{
+		Use:           "diff [old file] [new file]",
+		Short:         "compare two hives",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		Args:          cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			a, closeA, err := openHive(args[0])
+			if err != nil {
+				return err
+			}
+			defer closeA()
+			b, closeB, err := openHive(args[1])
+			if err != nil {
+				return err
+			}
+			defer closeB()
+
+			changes, err := regffs.Diff(a, b)
+			if err != nil {
+				return err
+			}
+
+			if jsonOutput {
+				return printJSON(changes)
+			}
+			for _, change := range changes {
+				fmt.Println(change)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "print changes as JSON")
+	return cmd
+}
+
+func openHive(name string) (*regffs.Regffs, func() error, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	fsys, err := regffs.New(f)
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	return fsys, f.Close, nil
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/forensicanalysis/regffs"
+)
+
+func grepCmd() *cobra.Command {
+	var options regffs.SearchOptions
+	cmd := &cobra.Command{
+		Use:           "grep [pattern] [file]",
+		Short:         "search key names, value names and value data",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		Args:          cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			fsys, closeHive, err := openHive(args[1])
+			if err != nil {
+				return err
+			}
+			defer closeHive()
+
+			options.ValueError = func(name string, err error) {
+				fmt.Fprintf(os.Stderr, "%s: %s\n", name, err)
+			}
+			matches, err := regffs.Search(fsys, args[0], options)
+			if err != nil {
+				return err
+			}
+			for _, match := range matches {
+				fmt.Println(match)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().BoolVarP(&options.Regex, "regex", "E", false, "interpret pattern as regular expression")
+	cmd.Flags().BoolVarP(&options.IgnoreCase, "ignore-case", "i", false, "ignore case distinctions")
+	cmd.Flags().BoolVar(&options.Names, "names", false, "only search key and value names")
+	cmd.Flags().BoolVar(&options.Data, "data", false, "only search value data")
+	cmd.Flags().BoolVar(&options.Deleted, "deleted", false, "include keys and values recovered from unallocated cells")
+	return cmd
+}
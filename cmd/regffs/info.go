@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/forensicanalysis/regffs"
+	"github.com/forensicanalysis/regffs/sysinfo"
+)
+
+func infoCmd() *cobra.Command {
+	var jsonOutput bool
+	cmd := &cobra.Command{
+		Use:           "info [SYSTEM|SOFTWARE]...",
+		Short:         "summarize the system profile",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		Args:          cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var hives []*regffs.Regffs
+			for _, arg := range args {
+				fsys, closeHive, err := openHive(arg)
+				if err != nil {
+					return err
+				}
+				defer closeHive()
+				hives = append(hives, fsys)
+			}
+
+			info, err := sysinfo.Summary(hives...)
+			if err != nil {
+				return err
+			}
+
+			if jsonOutput {
+				return printJSON(info)
+			}
+
+			rows := [][]string{
+				{"Computer name", info.ComputerName},
+				{"Hostname", info.Hostname},
+				{"Domain", info.Domain},
+				{"Product", strings.TrimSpace(info.ProductName + " " + info.DisplayVersion + " " + info.CSDVersion)},
+				{"Build", info.CurrentBuild},
+				{"Install date", formatTime(info.InstallDate)},
+				{"Registered owner", info.RegisteredOwner},
+				{"Registered organization", info.RegisteredOrganization},
+				{"Last shutdown", formatTime(info.LastShutdown)},
+			}
+			if info.TimeZone != nil {
+				rows = append(rows, []string{"Time zone", fmt.Sprintf("%s (bias %d min)", info.TimeZone.Name, info.TimeZone.Bias)})
+			}
+			for _, iface := range info.Interfaces {
+				mode := "static"
+				if iface.DHCP {
+					mode = "DHCP"
+				}
+				rows = append(rows, []string{"Interface " + iface.GUID, fmt.Sprintf("%s %s gateway %s dns %s",
+					mode, strings.Join(iface.IPAddresses, ","), strings.Join(iface.DefaultGateway, ","), strings.Join(iface.NameServers, ","))})
+			}
+
+			var set [][]string
+			for _, row := range rows {
+				if row[1] != "" && row[1] != "-" {
+					set = append(set, []string{row[0] + ":", row[1]})
+				}
+			}
+			return printTable(nil, set)
+		},
+	}
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "print the summary as JSON")
+	return cmd
+}
@@ -30,6 +30,7 @@ func main() {
 	for _, c := range cmd.Commands() {
 		c.Use += " [file]"
 	}
+	cmd.AddCommand(timelineCmd(), diffCmd(), grepCmd(), userAssistCmd(), shellBagsCmd(), appCompatCacheCmd(), samCmd(), autorunsCmd(), usbCmd(), servicesCmd(), mruCmd(), infoCmd(), amcacheCmd(), bamCmd(), compactCmd(), redactCmd(), carveCmd(), orphansCmd(), slackCmd(), whoisCmd())
 	if err := cmd.Execute(); err != nil {
 		fmt.Println(err)
 		os.Exit(1)
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"strconv"
+
+	"github.com/spf13/cobra"
+
+	"github.com/forensicanalysis/regffs/artifacts"
+)
+
+func mruCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:           "mru [NTUSER.DAT]",
+		Short:         "list most recently used entries",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		Args:          cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			fsys, closeHive, err := openHive(args[0])
+			if err != nil {
+				return err
+			}
+			defer closeHive()
+
+			entries, err := artifacts.MRUs(fsys)
+			if err != nil {
+				return err
+			}
+
+			var rows [][]string
+			for _, entry := range entries {
+				item := entry.Item
+				if entry.Program != "" {
+					item = entry.Program + ": " + item
+				}
+				rows = append(rows, []string{
+					formatTime(entry.KeyModTime),
+					entry.Key,
+					strconv.Itoa(entry.Position),
+					item,
+				})
+			}
+			return printTable([]string{"KEY MODIFIED", "KEY", "POSITION", "ITEM"}, rows)
+		},
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"io/fs"
+	"path"
+	"strconv"
+
+	"github.com/spf13/cobra"
+)
+
+func orphansCmd() *cobra.Command {
+	var jsonOutput, tree bool
+	cmd := &cobra.Command{
+		Use:           "orphans [file]",
+		Short:         "list allocated keys that are not reachable from the root key",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		Args:          cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			fsys, closeHive, err := openHive(args[0])
+			if err != nil {
+				return err
+			}
+			defer closeHive()
+
+			keys, err := fsys.OrphanedKeys()
+			if err != nil {
+				return err
+			}
+
+			type orphan struct {
+				Offset  int64    `json:"offset"`
+				Path    string   `json:"path"`
+				ModTime string   `json:"modified"`
+				Keys    int      `json:"keys"`
+				Values  int      `json:"values"`
+				Tree    []string `json:"tree,omitempty"`
+			}
+			var orphans []orphan
+			for _, key := range keys {
+				o := orphan{Offset: key.Offset, Path: key.Path, ModTime: formatTime(key.ModTime)}
+				_ = fs.WalkDir(key.Tree, ".", func(name string, d fs.DirEntry, err error) error {
+					if err != nil || name == "." {
+						return nil
+					}
+					if d.IsDir() {
+						o.Keys++
+					} else {
+						o.Values++
+					}
+					if tree {
+						o.Tree = append(o.Tree, path.Join(key.Path, name))
+					}
+					return nil
+				})
+				orphans = append(orphans, o)
+			}
+
+			if jsonOutput {
+				return printJSON(orphans)
+			}
+
+			var rows [][]string
+			for _, o := range orphans {
+				rows = append(rows, []string{strconv.FormatInt(o.Offset, 10), o.ModTime, strconv.Itoa(o.Keys), strconv.Itoa(o.Values), o.Path})
+				for _, name := range o.Tree {
+					rows = append(rows, []string{"", "", "", "", name})
+				}
+			}
+			return printTable([]string{"OFFSET", "MODIFIED", "SUBKEYS", "VALUES", "PATH"}, rows)
+		},
+	}
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "print the orphaned keys as JSON")
+	cmd.Flags().BoolVar(&tree, "tree", false, "list the subkeys and values of the orphaned keys")
+	return cmd
+}
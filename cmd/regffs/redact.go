@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/forensicanalysis/regffs"
+)
+
+func redactCmd() *cobra.Command {
+	var keys, classNames, valueNames, valueData []string
+	var regex bool
+	var secret, mappingFile string
+	cmd := &cobra.Command{
+		Use:           "redact [file] [output]",
+		Short:         "replace key names, class names, value names and value data with pseudonyms",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		Args:          cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if secret == "" {
+				return errors.New("missing --secret")
+			}
+			var rules []regffs.RedactRule
+			for _, pattern := range keys {
+				rules = append(rules, regffs.RedactRule{Pattern: pattern, Regex: regex, KeyNames: true})
+			}
+			for _, pattern := range classNames {
+				rules = append(rules, regffs.RedactRule{Pattern: pattern, Regex: regex, ClassNames: true})
+			}
+			for _, pattern := range valueNames {
+				rules = append(rules, regffs.RedactRule{Pattern: pattern, Regex: regex, ValueNames: true})
+			}
+			for _, pattern := range valueData {
+				rules = append(rules, regffs.RedactRule{Pattern: pattern, Regex: regex, ValueData: true})
+			}
+
+			src, closeSrc, err := openHive(args[0])
+			if err != nil {
+				return err
+			}
+			defer closeSrc()
+
+			dst, err := os.Create(args[1])
+			if err != nil {
+				return err
+			}
+			mapping, err := regffs.Redact(dst, src, []byte(secret), rules)
+			if err != nil {
+				dst.Close()
+				return err
+			}
+			if err := dst.Close(); err != nil {
+				return err
+			}
+
+			if mappingFile == "" {
+				return nil
+			}
+			b, err := json.MarshalIndent(mapping, "", "  ")
+			if err != nil {
+				return err
+			}
+			return os.WriteFile(mappingFile, b, 0o600)
+		},
+	}
+	cmd.Flags().StringArrayVar(&keys, "keys", nil, "redact names of keys matching the path pattern")
+	cmd.Flags().StringArrayVar(&classNames, "class-names", nil, "redact class names of keys matching the path pattern")
+	cmd.Flags().StringArrayVar(&valueNames, "value-names", nil, "redact names of values matching the path pattern")
+	cmd.Flags().StringArrayVar(&valueData, "value-data", nil, "redact data of values matching the path pattern")
+	cmd.Flags().BoolVarP(&regex, "regex", "E", false, "interpret patterns as regular expressions")
+	cmd.Flags().StringVar(&secret, "secret", "", "secret for deterministic pseudonyms")
+	cmd.Flags().StringVar(&mappingFile, "mapping", "", "write the mapping of originals to pseudonyms as JSON to this file")
+	return cmd
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/forensicanalysis/regffs/sam"
+)
+
+func samCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "sam",
+		Short: "extract accounts from a SAM hive",
+	}
+	cmd.AddCommand(samUsersCmd(), samGroupsCmd(), samHashesCmd())
+	return cmd
+}
+
+func samUsersCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:           "users [SAM]",
+		Short:         "list local user accounts",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		Args:          cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			fsys, closeHive, err := openHive(args[0])
+			if err != nil {
+				return err
+			}
+			defer closeHive()
+
+			users, err := sam.Users(fsys)
+			if err != nil {
+				return err
+			}
+
+			var rows [][]string
+			for _, user := range users {
+				rows = append(rows, []string{
+					strconv.Itoa(int(user.RID)),
+					user.Name,
+					formatTime(user.LastLogon),
+					formatTime(user.PasswordLastSet),
+					strconv.Itoa(int(user.LogonCount)),
+					strings.Join(user.FlagNames(), ", "),
+				})
+			}
+			return printTable([]string{"RID", "NAME", "LAST LOGON", "PASSWORD SET", "LOGONS", "FLAGS"}, rows)
+		},
+	}
+}
+
+func samGroupsCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:           "groups [SAM]",
+		Short:         "list local groups and their members",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		Args:          cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			fsys, closeHive, err := openHive(args[0])
+			if err != nil {
+				return err
+			}
+			defer closeHive()
+
+			groups, err := sam.Groups(fsys)
+			if err != nil {
+				return err
+			}
+
+			var rows [][]string
+			for _, group := range groups {
+				members := group.MemberSIDs
+				for _, rid := range group.MemberRIDs {
+					members = append(members, strconv.Itoa(int(rid)))
+				}
+				rows = append(rows, []string{
+					group.Domain,
+					strconv.Itoa(int(group.RID)),
+					group.Name,
+					strings.Join(members, ", "),
+				})
+			}
+			return printTable([]string{"DOMAIN", "RID", "NAME", "MEMBERS"}, rows)
+		},
+	}
+}
+
+func samHashesCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:           "hashes [SAM] [SYSTEM]",
+		Short:         "decrypt password hashes in pwdump format",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		Args:          cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			samHive, closeSAM, err := openHive(args[0])
+			if err != nil {
+				return err
+			}
+			defer closeSAM()
+			systemHive, closeSystem, err := openHive(args[1])
+			if err != nil {
+				return err
+			}
+			defer closeSystem()
+
+			hashes, err := sam.Hashes(samHive, systemHive)
+			if err != nil {
+				return err
+			}
+			for _, hash := range hashes {
+				fmt.Println(hash.Pwdump())
+			}
+			return nil
+		},
+	}
+}
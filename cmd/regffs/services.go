@@ -0,0 +1,54 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/forensicanalysis/regffs/artifacts"
+)
+
+func servicesCmd() *cobra.Command {
+	var suspicious bool
+	cmd := &cobra.Command{
+		Use:           "services [SYSTEM]",
+		Short:         "list services and drivers",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		Args:          cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			fsys, closeHive, err := openHive(args[0])
+			if err != nil {
+				return err
+			}
+			defer closeHive()
+
+			services, err := artifacts.Services(fsys)
+			if err != nil {
+				return err
+			}
+
+			var rows [][]string
+			for _, service := range services {
+				if suspicious && len(service.Suspicious) == 0 {
+					continue
+				}
+				imagePath := service.ImagePath
+				if service.ServiceDll != "" {
+					imagePath += " (" + service.ServiceDll + ")"
+				}
+				rows = append(rows, []string{
+					formatTime(service.KeyModTime),
+					service.Name,
+					service.StartName(),
+					strings.Join(service.TypeNames(), ", "),
+					imagePath,
+					strings.Join(service.Suspicious, ", "),
+				})
+			}
+			return printTable([]string{"KEY MODIFIED", "NAME", "START", "TYPE", "IMAGE PATH", "SUSPICIOUS"}, rows)
+		},
+	}
+	cmd.Flags().BoolVar(&suspicious, "suspicious", false, "only list services with suspicious image paths")
+	return cmd
+}
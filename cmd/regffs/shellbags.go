@@ -0,0 +1,44 @@
+package main
+
+import (
+	"strconv"
+
+	"github.com/spf13/cobra"
+
+	"github.com/forensicanalysis/regffs/shellbags"
+)
+
+func shellBagsCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:           "shellbags [NTUSER.DAT or UsrClass.dat]",
+		Short:         "decode ShellBags folder access entries",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		Args:          cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			fsys, closeHive, err := openHive(args[0])
+			if err != nil {
+				return err
+			}
+			defer closeHive()
+
+			bags, err := shellbags.ShellBags(fsys)
+			if err != nil {
+				return err
+			}
+
+			var rows [][]string
+			for _, bag := range bags {
+				rows = append(rows, []string{
+					formatTime(bag.KeyModTime),
+					formatTime(bag.Item.Modified),
+					formatTime(bag.Item.Created),
+					formatTime(bag.Item.Accessed),
+					strconv.Itoa(bag.Slot),
+					bag.Path,
+				})
+			}
+			return printTable([]string{"KEY MTIME", "MODIFIED", "CREATED", "ACCESSED", "SLOT", "PATH"}, rows)
+		},
+	}
+}
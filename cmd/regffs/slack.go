@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/spf13/cobra"
+)
+
+func slackCmd() *cobra.Command {
+	var jsonOutput, strs bool
+	var minLength int
+	var output string
+	cmd := &cobra.Command{
+		Use:           "slack [file]",
+		Short:         "list free space and cell slack",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		Args:          cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			fsys, closeHive, err := openHive(args[0])
+			if err != nil {
+				return err
+			}
+			defer closeHive()
+
+			regions, err := fsys.Regions()
+			if err != nil {
+				return err
+			}
+
+			if output != "" {
+				for _, region := range regions {
+					data, err := fsys.ReadRegion(region)
+					if err != nil {
+						return err
+					}
+					name := filepath.Join(output, fmt.Sprintf("%s_%d.bin", region.Type, region.Offset))
+					if err := os.WriteFile(name, data, 0o644); err != nil {
+						return err
+					}
+				}
+			}
+
+			if strs {
+				found, err := fsys.RegionStrings(regions, minLength)
+				if err != nil {
+					return err
+				}
+				if jsonOutput {
+					return printJSON(found)
+				}
+				var rows [][]string
+				for _, s := range found {
+					rows = append(rows, []string{strconv.FormatInt(s.Offset, 10), string(s.Region.Type), s.Encoding, strconv.Quote(s.String)})
+				}
+				return printTable([]string{"OFFSET", "TYPE", "ENCODING", "STRING"}, rows)
+			}
+
+			if jsonOutput {
+				return printJSON(regions)
+			}
+			var rows [][]string
+			for _, region := range regions {
+				rows = append(rows, []string{strconv.FormatInt(region.Offset, 10), string(region.Type), strconv.FormatInt(region.Size, 10), strconv.FormatInt(region.Cell, 10)})
+			}
+			return printTable([]string{"OFFSET", "TYPE", "SIZE", "CELL"}, rows)
+		},
+	}
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "print the regions or strings as JSON")
+	cmd.Flags().BoolVarP(&strs, "strings", "s", false, "extract ASCII and UTF-16 strings from the regions")
+	cmd.Flags().IntVarP(&minLength, "min-length", "n", 4, "minimum length of extracted strings")
+	cmd.Flags().StringVarP(&output, "output", "o", "", "dump the regions to this directory")
+	return cmd
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/forensicanalysis/regffs"
+)
+
+func timelineCmd() *cobra.Command {
+	var deleted bool
+	var prefix string
+	cmd := &cobra.Command{
+		Use:           "timeline [file]",
+		Short:         "print key last written times in bodyfile format",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		Args:          cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			fsys, closeHive, err := openHive(args[0])
+			if err != nil {
+				return err
+			}
+			defer closeHive()
+
+			entries, err := regffs.Timeline(fsys)
+			if err != nil {
+				return err
+			}
+			if deleted {
+				deletedEntries, err := fsys.DeletedTimeline()
+				if err != nil {
+					return err
+				}
+				entries = append(entries, deletedEntries...)
+			}
+
+			if !cmd.Flags().Changed("prefix") {
+				prefix = filepath.Base(args[0])
+			}
+			return regffs.WriteBodyfile(os.Stdout, prefix, entries)
+		},
+	}
+	cmd.Flags().BoolVar(&deleted, "deleted", false, "include keys recovered from unallocated cells")
+	cmd.Flags().StringVar(&prefix, "prefix", "", "path prefix, defaults to the hive file name")
+	return cmd
+}
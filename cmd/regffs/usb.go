@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/forensicanalysis/regffs"
+	"github.com/forensicanalysis/regffs/artifacts"
+)
+
+func usbCmd() *cobra.Command {
+	var software string
+	var users []string
+	cmd := &cobra.Command{
+		Use:           "usb [SYSTEM]",
+		Short:         "list USB storage device history",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		Args:          cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			system, closeSystem, err := openHive(args[0])
+			if err != nil {
+				return err
+			}
+			defer closeSystem()
+
+			var softwareHive *regffs.Regffs
+			if software != "" {
+				var closeSoftware func() error
+				softwareHive, closeSoftware, err = openHive(software)
+				if err != nil {
+					return err
+				}
+				defer closeSoftware()
+			}
+
+			userHives := map[string]*regffs.Regffs{}
+			for _, user := range users {
+				name, file, ok := strings.Cut(user, "=")
+				if !ok {
+					return fmt.Errorf("invalid user %q, expected name=NTUSER.DAT", user)
+				}
+				userHive, closeUser, err := openHive(file)
+				if err != nil {
+					return err
+				}
+				defer closeUser()
+				userHives[name] = userHive
+			}
+
+			devices, err := artifacts.USBDevices(system, softwareHive, userHives)
+			if err != nil {
+				return err
+			}
+
+			var rows [][]string
+			for _, device := range devices {
+				var userNames []string
+				for _, user := range device.Users {
+					userNames = append(userNames, user.Name)
+				}
+				rows = append(rows, []string{
+					formatTime(device.FirstInstall),
+					formatTime(device.LastArrival),
+					device.Vendor + " " + device.Product,
+					device.Serial,
+					device.DriveLetter,
+					device.VolumeName,
+					strings.Join(userNames, ", "),
+				})
+			}
+			return printTable([]string{"FIRST INSTALL", "LAST ARRIVAL", "DEVICE", "SERIAL", "DRIVE", "VOLUME", "USERS"}, rows)
+		},
+	}
+	cmd.Flags().StringVar(&software, "software", "", "SOFTWARE hive for volume names")
+	cmd.Flags().StringArrayVar(&users, "user", nil, "NTUSER.DAT hive of a user as name=file, can be repeated")
+	return cmd
+}
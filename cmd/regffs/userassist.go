@@ -0,0 +1,42 @@
+package main
+
+import (
+	"strconv"
+
+	"github.com/spf13/cobra"
+
+	"github.com/forensicanalysis/regffs/artifacts"
+)
+
+func userAssistCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:           "userassist [NTUSER.DAT]",
+		Short:         "decode UserAssist program execution entries",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		Args:          cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			fsys, closeHive, err := openHive(args[0])
+			if err != nil {
+				return err
+			}
+			defer closeHive()
+
+			entries, err := artifacts.UserAssist(fsys)
+			if err != nil {
+				return err
+			}
+
+			var rows [][]string
+			for _, entry := range entries {
+				rows = append(rows, []string{
+					formatTime(entry.LastRun),
+					strconv.Itoa(int(entry.RunCount)),
+					entry.FocusTime.String(),
+					entry.Name,
+				})
+			}
+			return printTable([]string{"LAST RUN", "COUNT", "FOCUS", "NAME"}, rows)
+		},
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"strconv"
+
+	"github.com/spf13/cobra"
+)
+
+func whoisCmd() *cobra.Command {
+	var fileOffset bool
+	cmd := &cobra.Command{
+		Use:           "whois [file] [offset]",
+		Short:         "resolve the key or value path of a cell offset",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		Args:          cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			offset, err := strconv.ParseInt(args[1], 0, 64)
+			if err != nil {
+				return err
+			}
+			if fileOffset {
+				// skip the base block
+				offset -= 0x1000
+			}
+
+			fsys, closeHive, err := openHive(args[0])
+			if err != nil {
+				return err
+			}
+			defer closeHive()
+
+			cell, err := fsys.CellAt(offset)
+			if err != nil {
+				return err
+			}
+			p, err := fsys.PathOf(offset)
+			if err != nil {
+				p = err.Error()
+			}
+			return printTable([]string{"CELL", "SIZE", "SIGNATURE", "ALLOCATED", "PATH"}, [][]string{{
+				"0x" + strconv.FormatInt(cell.Offset, 16),
+				strconv.FormatInt(cell.Size, 10),
+				strconv.Quote(cell.Signature),
+				strconv.FormatBool(cell.Allocated),
+				p,
+			}})
+		},
+	}
+	cmd.Flags().BoolVar(&fileOffset, "file-offset", false, "the offset is relative to the start of the file instead of the hive bins")
+	return cmd
+}
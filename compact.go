@@ -0,0 +1,176 @@
+package regffs
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"path"
+)
+
+// Header fields copied by Compact. The file name is the UTF-16 end of the
+// hive path and is used by Windows for diagnostics only.
+var compactHeaderFields = [][2]uint32{
+	{headerMajorVersion, 4},
+	{headerMinorVersion, 4},
+	{headerFileType, 4},
+	{headerFormat, 4},
+	{headerClusteringFactor, 4},
+	{headerFileName, 64},
+}
+
+// Compact writes the keys, values and security descriptors of src that
+// are reachable from the root key to dst, packed into new hive bins. Free
+// cells, cell slack and deleted keys and values are not copied. Key names,
+// class names, flags and last written times are kept, the version of src
+// determines the subkey list and big data format.
+func Compact(dst io.Writer, src *Regffs) error {
+	return newCompactor(src).compact(dst)
+}
+
+func newCompactor(src *Regffs) *compactor {
+	return &compactor{h: newHive(), src: src, security: map[uint32]uint32{}, head: noCell}
+}
+
+// compact copies the keys of src to the new hive and writes it to dst.
+func (c *compactor) compact(dst io.Writer) error {
+	base := make([]byte, baseBlockSize)
+	if _, err := c.src.reader.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := io.ReadFull(c.src.reader, base); err != nil {
+		return err
+	}
+	for _, field := range compactHeaderFields {
+		// the file name contains the user profile path
+		if field[0] == headerFileName && c.redactor != nil {
+			continue
+		}
+		copy(c.h.data[field[0]:field[0]+field[1]], base[field[0]:])
+	}
+	root, err := c.src.Open(".")
+	if err != nil {
+		return err
+	}
+	nk, err := c.copyKey(root.(*File), ".", noCell, 0)
+	if err != nil {
+		return err
+	}
+	c.h.putUint32(headerRootKeyOffset, nk)
+	_, err = c.h.WriteTo(dst)
+	return err
+}
+
+type compactor struct {
+	h   *Hive
+	src *Regffs
+	// security maps the offsets of the security cells of src to h.
+	security map[uint32]uint32
+	// head is the first security cell of h.
+	head uint32
+	// redactor is optional and renames keys and values.
+	redactor *redactor
+}
+
+// copyKey copies the key f at name with its values and subkeys and returns
+// the offset of the new key.
+func (c *compactor) copyKey(f *File, name string, parent uint32, depth int) (uint32, error) {
+	if depth > maxKeyDepth {
+		return 0, fmt.Errorf("key %s: too deeply nested", f.Name())
+	}
+	srcNK := f.Sys().(*NamedKey)
+	sk, err := c.copySecurity(srcNK.SecurityKeyOffset())
+	if err != nil {
+		return 0, fmt.Errorf("key %s: %w", f.Name(), err)
+	}
+	keyName := f.Name()
+	if c.redactor != nil && name != "." {
+		keyName = c.redactor.keyName(name, keyName)
+	}
+	nk, err := c.h.newKey(parent, keyName, sk)
+	if err != nil {
+		return 0, err
+	}
+	c.h.put16(nk, nkFlags, srcNK.Flags()&^NkFlags.KeyCompName|c.h.get16(nk, nkFlags)&NkFlags.KeyCompName)
+
+	className, err := f.ClassName()
+	if err != nil {
+		return 0, fmt.Errorf("key %s: %w", f.Name(), err)
+	}
+	if c.redactor != nil {
+		className = c.redactor.className(name, className)
+	}
+	if err := c.h.setClassName(nk, className); err != nil {
+		return 0, err
+	}
+
+	entries, err := f.ReadDir(-1)
+	if err != nil {
+		return 0, err
+	}
+	var values, subkeys []uint32
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		v, err := entry.(*File).Value()
+		if err != nil {
+			return 0, fmt.Errorf("value %s: %w", entry.Name(), err)
+		}
+		if c.redactor != nil {
+			v = c.redactor.value(path.Join(name, v.Name), v)
+		}
+		vk, err := c.h.newValue(v)
+		if err != nil {
+			return 0, err
+		}
+		values = append(values, vk)
+	}
+	if err := c.h.setValues(nk, values); err != nil {
+		return 0, err
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		subkey, err := c.copyKey(entry.(*File), path.Join(name, entry.Name()), nk, depth+1)
+		if err != nil {
+			return 0, err
+		}
+		subkeys = append(subkeys, subkey)
+	}
+	if err := c.h.setSubkeys(nk, subkeys); err != nil {
+		return 0, err
+	}
+
+	c.h.put64(nk, nkLastWritten, srcNK.LastKeyWrittenDateAndTime().Value())
+	return nk, nil
+}
+
+// copySecurity copies the security cell at offset of src once and returns
+// the offset of the copy.
+func (c *compactor) copySecurity(offset uint32) (uint32, error) {
+	if sk, ok := c.security[offset]; ok {
+		return sk, nil
+	}
+	header, err := readCellData(c.src.reader, int64(offset)+hiveBinsOffset, skDescriptor)
+	if err != nil {
+		return 0, err
+	}
+	if string(header[:2]) != "sk" {
+		return 0, fmt.Errorf("no security cell at 0x%x", offset)
+	}
+	size := binary.LittleEndian.Uint32(header[skDescriptorSize:])
+	data, err := readCellData(c.src.reader, int64(offset)+hiveBinsOffset, skDescriptor+size)
+	if err != nil {
+		return 0, err
+	}
+	sk, err := c.h.newSecurity(data[skDescriptor:], c.head)
+	if err != nil {
+		return 0, err
+	}
+	if c.head == noCell {
+		c.head = sk
+	}
+	c.security[offset] = sk
+	return sk, nil
+}
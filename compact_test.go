@@ -0,0 +1,110 @@
+package regffs
+
+import (
+	"bytes"
+	"os"
+	"testing"
+	"testing/fstest"
+)
+
+func TestCompact(t *testing.T) {
+	b, err := os.ReadFile("testdata/NTUSER.DAT")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, err := OpenHive(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := h.DeleteKey("Software/Microsoft"); err != nil {
+		t.Fatal(err)
+	}
+	src, err := h.Regffs()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := Compact(&buf, src); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() >= len(h.Bytes()) {
+		t.Errorf("compacted size %d >= %d", buf.Len(), len(h.Bytes()))
+	}
+	dst, err := New(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changes, err := Diff(src, dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, change := range changes {
+		t.Error(change)
+	}
+	for _, name := range []string{"Software/Intel/Indeo", "Control Panel/Desktop"} {
+		want, _ := src.ClassName(name)
+		got, err := dst.ClassName(name)
+		if err != nil || got != want {
+			t.Errorf("ClassName(%s) = %q, %v, want %q", name, got, err, want)
+		}
+	}
+	deleted, err := dst.DeletedKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deleted) != 0 {
+		t.Errorf("DeletedKeys() = %d keys, want none", len(deleted))
+	}
+}
+
+func TestCompactFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a/b/value": {Data: []byte("data"), Sys: DataTypeEnum.RegBinary},
+		"a/c":       {Data: []byte("s\x00\x00\x00"), Sys: DataTypeEnum.RegSz},
+		"d":         {Data: bytes.Repeat([]byte{1}, 20000)},
+	}
+	var built bytes.Buffer
+	if err := Build(&built, fsys); err != nil {
+		t.Fatal(err)
+	}
+	src, err := New(bytes.NewReader(built.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var compacted bytes.Buffer
+	if err := Compact(&compacted, src); err != nil {
+		t.Fatal(err)
+	}
+	dst, err := New(bytes.NewReader(compacted.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fstest.TestFS(dst, "a/b/value", "a/c", "d"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCompactUTF16Names(t *testing.T) {
+	h, err := NewHive()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := h.CreateKey("дом/Latin-ü"); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.SetValue("дом", &Value{Name: "имя", Type: DataTypeEnum.RegDword, Data: []byte{1, 0, 0, 0}}); err != nil {
+		t.Fatal(err)
+	}
+	src, err := h.Regffs()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := Compact(&buf, src); err != nil {
+		t.Fatal(err)
+	}
+	checkNames(t, buf.Bytes())
+}
@@ -0,0 +1,153 @@
+package regffs
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"path"
+	"time"
+)
+
+const (
+	hiveBinsOffset   = 0x1000
+	hiveBinAlignment = 0x1000
+	hiveBinHeaderLen = 0x20
+	maxKeyDepth      = 512
+)
+
+// DeletedKey is a key recovered from an unallocated cell.
+type DeletedKey struct {
+	// Offset of the cell, relative to the start of the hive bins data.
+	Offset int64
+	Name   string
+	// Path is reconstructed from the parent key offsets. Parents that can
+	// not be resolved are replaced by "?".
+	Path    string
+	ModTime time.Time
+	Key     *NamedKey
+}
+
+// DeletedKeys scans all hive bins for unallocated key cells.
+func (r *Regffs) DeletedKeys() ([]*DeletedKey, error) {
+	var keys []*DeletedKey
+	err := r.walkCells(func(offset int64, cell *HiveBinCell) error {
+		if cell.IsAllocated() {
+			return nil
+		}
+		nk, ok := cell.Data().(*NamedKey)
+		if !ok || !validNamedKey(cell, nk) {
+			return nil
+		}
+		name := decodeKeyName(nk)
+		keys = append(keys, &DeletedKey{
+			Offset:  offset,
+			Name:    name,
+			Path:    path.Join(r.parentPath(nk), name),
+			ModTime: FiletimeToTime(nk.LastKeyWrittenDateAndTime().Value()),
+			Key:     nk,
+		})
+		return nil
+	})
+	return keys, err
+}
+
+// errStop stops walkCells without error.
+var errStop = errors.New("stop")
+
+// walkCells calls fn for every cell, allocated or not, in every hive bin.
+// Offsets passed to fn are relative to the start of the hive bins data.
+func (r *Regffs) walkCells(fn func(offset int64, cell *HiveBinCell) error) error {
+	it := r.Cells()
+	for it.Next() {
+		if err := fn(it.Cell().Offset, it.Cell().cell); err != nil {
+			return err
+		}
+	}
+	return it.Err()
+}
+
+// parentPath resolves the path of the parent of nk. The root key itself has
+// the empty path.
+func (r *Regffs) parentPath(nk *NamedKey) string {
+	p, _ := r.keyPath(nk.ParentKeyOffset())
+	return p
+}
+
+// validNamedKey reports whether the key name of nk fits into its cell.
+func validNamedKey(cell *HiveBinCell, nk *NamedKey) bool {
+	const nkHeaderLen = 0x50
+	return nk.UnknownStringSize() > 0 && int64(nk.UnknownStringSize())+nkHeaderLen <= cell.CellSize()
+}
+
+func reverse(s []string) {
+	for i, j := 0, len(s)-1; i < j; i, j = i+1, j-1 {
+		s[i], s[j] = s[j], s[i]
+	}
+}
+
+// DeletedValue is a value recovered from an unallocated cell.
+type DeletedValue struct {
+	// Offset of the cell, relative to the start of the hive bins data.
+	Offset int64
+	Name   string
+	// Path is the path of the value if it is referenced by a deleted key,
+	// otherwise the name prefixed with "?".
+	Path string
+	// Value is nil if the data could not be recovered.
+	Value *Value
+}
+
+// DeletedValues scans all hive bins for unallocated value cells.
+func (r *Regffs) DeletedValues() ([]*DeletedValue, error) {
+	keys, err := r.DeletedKeys()
+	if err != nil {
+		return nil, err
+	}
+	keyPaths := map[int64]string{}
+	for _, key := range keys {
+		for _, offset := range r.valueListOffsets(key.Key) {
+			keyPaths[offset] = key.Path
+		}
+	}
+
+	var values []*DeletedValue
+	err = r.walkCells(func(offset int64, cell *HiveBinCell) error {
+		if _, ok := cell.Data().(*SubKeyListVk); !ok || cell.IsAllocated() {
+			return nil
+		}
+		f := &File{reader: r.reader, cell: cell, regf: r.regf, warnings: r.warnings}
+		name := f.Name()
+		keyPath, ok := keyPaths[offset]
+		if !ok {
+			keyPath = "?"
+		}
+		value, err := f.Value()
+		if err != nil {
+			value = nil
+		}
+		values = append(values, &DeletedValue{Offset: offset, Name: name, Path: path.Join(keyPath, name), Value: value})
+		return nil
+	})
+	return values, err
+}
+
+// valueListOffsets returns the value cell offsets referenced by nk.
+func (r *Regffs) valueListOffsets(nk *NamedKey) []int64 {
+	const maxValues = 0x10000
+	if nk.NumberOfValues() == 0 || nk.NumberOfValues() > maxValues || nk.ValuesListOffset() == 0xffffffff {
+		return nil
+	}
+	_, err := r.reader.Seek(int64(nk.ValuesListOffset())+hiveBinsOffset+4, io.SeekStart)
+	if err != nil {
+		return nil
+	}
+	list := make([]uint32, nk.NumberOfValues())
+	if err := binary.Read(r.reader, binary.LittleEndian, list); err != nil {
+		return nil
+	}
+	offsets := make([]int64, 0, len(list))
+	for _, offset := range list {
+		offsets = append(offsets, int64(offset))
+	}
+	return offsets
+}
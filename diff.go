@@ -0,0 +1,148 @@
+package regffs
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"sort"
+	"time"
+)
+
+// ChangeType describes how a key or value differs between two hives.
+type ChangeType string
+
+const (
+	Added    ChangeType = "added"
+	Removed  ChangeType = "removed"
+	Modified ChangeType = "modified"
+)
+
+// Change is a difference of a single key or value.
+type Change struct {
+	Type ChangeType `json:"type"`
+	Path string     `json:"path"`
+	Key  bool       `json:"key"`
+	Old  *DiffEntry `json:"old,omitempty"`
+	New  *DiffEntry `json:"new,omitempty"`
+}
+
+// DiffEntry is the state of a key or value in one of the compared hives.
+// Keys only carry the last written time, values only the typed data.
+type DiffEntry struct {
+	ModTime  *time.Time  `json:"mtime,omitempty"`
+	DataType string      `json:"data_type,omitempty"`
+	Data     interface{} `json:"data,omitempty"`
+
+	value *Value
+}
+
+func (c *Change) String() string {
+	sign := map[ChangeType]string{Added: "+", Removed: "-", Modified: "~"}[c.Type]
+	kind := "value"
+	if c.Key {
+		kind = "key"
+	}
+	s := fmt.Sprintf("%s %s %s", sign, kind, c.Path)
+	if c.Old != nil {
+		s += "\n    old: " + c.Old.String()
+	}
+	if c.New != nil {
+		s += "\n    new: " + c.New.String()
+	}
+	return s
+}
+
+func (e *DiffEntry) String() string {
+	if e.value != nil {
+		return fmt.Sprintf("%s %s", e.DataType, e.value)
+	}
+	if e.ModTime != nil {
+		return e.ModTime.Format(time.RFC3339)
+	}
+	return ""
+}
+
+// Diff compares two hives, e.g. a baseline and a compromised hive, and
+// returns the added, removed and modified keys and values of b compared to
+// a. Keys are modified if their last written time differs, values if their
+// data type or data differs.
+func Diff(a, b fs.FS) ([]*Change, error) {
+	entriesA, err := diffEntries(a)
+	if err != nil {
+		return nil, err
+	}
+	entriesB, err := diffEntries(b)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []*Change
+	for id, old := range entriesA {
+		n, ok := entriesB[id]
+		switch {
+		case !ok:
+			changes = append(changes, &Change{Type: Removed, Path: id.path, Key: id.key, Old: old})
+		case !old.equal(n):
+			changes = append(changes, &Change{Type: Modified, Path: id.path, Key: id.key, Old: old, New: n})
+		}
+	}
+	for id, n := range entriesB {
+		if _, ok := entriesA[id]; !ok {
+			changes = append(changes, &Change{Type: Added, Path: id.path, Key: id.key, New: n})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Path != changes[j].Path {
+			return changes[i].Path < changes[j].Path
+		}
+		return changes[i].Key && !changes[j].Key
+	})
+	return changes, nil
+}
+
+// diffID separates keys and values with the same name.
+type diffID struct {
+	path string
+	key  bool
+}
+
+func diffEntries(fsys fs.FS) (map[diffID]*DiffEntry, error) {
+	entries := map[diffID]*DiffEntry{}
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if name == "." {
+			return nil
+		}
+		if d.IsDir() {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			modTime := info.ModTime()
+			entries[diffID{name, true}] = &DiffEntry{ModTime: &modTime}
+			return nil
+		}
+
+		value, err := readValue(fsys, name, d)
+		if err != nil {
+			return err
+		}
+		data, err := value.Interface()
+		if err != nil {
+			data = value.Data
+		}
+		entries[diffID{name, false}] = &DiffEntry{DataType: value.TypeName(), Data: data, value: value}
+		return nil
+	})
+	return entries, err
+}
+
+func (e *DiffEntry) equal(o *DiffEntry) bool {
+	if e.value != nil || o.value != nil {
+		return e.value != nil && o.value != nil && e.value.Type == o.value.Type && bytes.Equal(e.value.Data, o.value.Data)
+	}
+	return e.ModTime.Equal(*o.ModTime)
+}
@@ -0,0 +1,105 @@
+package regffs
+
+import (
+	"os"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestDiff(t *testing.T) {
+	t1 := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	t2 := t1.Add(time.Hour)
+	a := fstest.MapFS{
+		"Run":          {Mode: 0o755 | os.ModeDir, ModTime: t1},
+		"Run/Updater":  {Data: []byte("a")},
+		"Run/Removed":  {Data: []byte("b")},
+		"Run/Constant": {Data: []byte("c")},
+	}
+	b := fstest.MapFS{
+		"Run":          {Mode: 0o755 | os.ModeDir, ModTime: t2},
+		"Run/Updater":  {Data: []byte("x")},
+		"Run/Added":    {Data: []byte("d")},
+		"Run/Constant": {Data: []byte("c")},
+	}
+
+	changes, err := Diff(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []struct {
+		changeType ChangeType
+		path       string
+		key        bool
+	}{
+		{Modified, "Run", true},
+		{Added, "Run/Added", false},
+		{Removed, "Run/Removed", false},
+		{Modified, "Run/Updater", false},
+	}
+	if len(changes) != len(expected) {
+		t.Fatalf("got %d changes, want %d: %v", len(changes), len(expected), changes)
+	}
+	for i, e := range expected {
+		c := changes[i]
+		if c.Type != e.changeType || c.Path != e.path || c.Key != e.key {
+			t.Errorf("change %d: got %s %s %v, want %s %s %v", i, c.Type, c.Path, c.Key, e.changeType, e.path, e.key)
+		}
+	}
+}
+
+func TestDiffSame(t *testing.T) {
+	f, err := os.Open("testdata/NTUSER.DAT")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	fsys, err := New(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changes, err := Diff(fsys, fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 0 {
+		t.Errorf("got %d changes comparing a hive with itself", len(changes))
+	}
+}
+
+func TestValue(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		dataType string
+		expected string
+	}{
+		{"REG_SZ", "Software/Microsoft/Windows/CurrentVersion/Explorer/Logon User Name", "REG_SZ", "joe"},
+		{"REG_DWORD zero", "Control Panel/Desktop/PaintDesktopVersion", "REG_DWORD", "0"},
+		{"REG_DWORD", "Control Panel/Desktop/FontSmoothingType", "REG_DWORD", "1"},
+	}
+	f, err := os.Open("testdata/NTUSER.DAT")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	fsys, err := New(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, err := fsys.Value(tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if value.TypeName() != tt.dataType || value.String() != tt.expected {
+				t.Errorf("got %s %q, want %s %q", value.TypeName(), value.String(), tt.dataType, tt.expected)
+			}
+		})
+	}
+}
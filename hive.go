@@ -0,0 +1,401 @@
+package regffs
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+	"unicode/utf16"
+)
+
+const (
+	baseBlockSize  = 0x1000
+	checksumOffset = 0x1fc
+	cellAlignment  = 8
+	// noCell marks unset cell offsets.
+	noCell = 0xffffffff
+)
+
+// Header fields of the base block.
+const (
+	headerPrimarySequence   = 0x04
+	headerSecondarySequence = 0x08
+	headerTimestamp         = 0x0c
+	headerMajorVersion      = 0x14
+	headerMinorVersion      = 0x18
+	headerFileType          = 0x1c
+	headerFormat            = 0x20
+	headerRootKeyOffset     = 0x24
+	headerHiveBinsDataSize  = 0x28
+	headerClusteringFactor  = 0x2c
+	headerFileName          = 0x30
+)
+
+// Hive is a hive held in memory that can be modified and saved. Keys and
+// values are addressed by slash separated paths relative to the root key,
+// like in the fs.FS of Regffs, but are matched case insensitive like in
+// Windows.
+type Hive struct {
+	data []byte
+	bins []hiveBin
+	// free are the unallocated cells sorted by offset.
+	free []freeCell
+	// Now returns the time used for key last written times and the header
+	// timestamp. It defaults to time.Now.
+	Now func() time.Time
+}
+
+type hiveBin struct {
+	offset, size uint32
+}
+
+type freeCell struct {
+	offset, size uint32
+}
+
+// NewHive creates an empty hive with a root key and a default security
+// descriptor.
+func NewHive() (*Hive, error) {
+	h := newHive()
+	sk, err := h.newSecurity(defaultSecurityDescriptor(), noCell)
+	if err != nil {
+		return nil, err
+	}
+	root, err := h.newKey(noCell, "ROOT", sk)
+	if err != nil {
+		return nil, err
+	}
+	h.put16(root, nkFlags, h.get16(root, nkFlags)|NkFlags.KeyHiveEntry|NkFlags.KeyNoDelete)
+	h.putUint32(headerRootKeyOffset, root)
+	return h, nil
+}
+
+// newHive creates a hive with a base block of version 1.5 and without
+// hive bins.
+func newHive() *Hive {
+	h := &Hive{data: make([]byte, baseBlockSize), Now: time.Now}
+	copy(h.data, "regf")
+	h.putUint32(headerMajorVersion, 1)
+	h.putUint32(headerMinorVersion, 5)
+	h.putUint32(headerFileType, 0)
+	h.putUint32(headerFormat, 1)
+	h.putUint32(headerClusteringFactor, 1)
+	return h
+}
+
+// OpenHive loads a hive from r for modification.
+func OpenHive(r io.Reader) (*Hive, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < baseBlockSize || string(data[:4]) != "regf" {
+		return nil, errors.New("not a regf file")
+	}
+	h := &Hive{data: data, Now: time.Now}
+
+	end := uint32(len(data))
+	if size := h.uint32(headerHiveBinsDataSize); size != 0 && hiveBinsOffset+uint64(size) <= uint64(len(data)) {
+		end = hiveBinsOffset + size
+	}
+	h.data = h.data[:end]
+	for offset := uint32(hiveBinsOffset); offset+hiveBinHeaderLen <= end; {
+		if string(h.data[offset:offset+4]) != "hbin" {
+			break
+		}
+		size := binary.LittleEndian.Uint32(h.data[offset+8:])
+		if size < hiveBinAlignment || size%hiveBinAlignment != 0 || offset+size > end {
+			return nil, fmt.Errorf("invalid hive bin at 0x%x", offset)
+		}
+		bin := hiveBin{offset - hiveBinsOffset, size}
+		if err := h.scanBin(bin); err != nil {
+			return nil, err
+		}
+		h.bins = append(h.bins, bin)
+		offset += size
+	}
+	if len(h.bins) == 0 {
+		return nil, errors.New("no hive bins")
+	}
+	last := h.bins[len(h.bins)-1]
+	h.data = h.data[:hiveBinsOffset+last.offset+last.size]
+	return h, nil
+}
+
+// scanBin adds the unallocated cells of a bin to the free list.
+func (h *Hive) scanBin(bin hiveBin) error {
+	for offset := bin.offset + hiveBinHeaderLen; offset < bin.offset+bin.size; {
+		size := int32(binary.LittleEndian.Uint32(h.data[hiveBinsOffset+offset:]))
+		abs := size
+		if abs < 0 {
+			abs = -abs
+		}
+		if abs < cellAlignment || offset+uint32(abs) > bin.offset+bin.size {
+			return fmt.Errorf("invalid cell size at 0x%x", offset)
+		}
+		if size > 0 {
+			h.free = append(h.free, freeCell{offset, uint32(size)})
+		}
+		offset += uint32(abs)
+	}
+	return nil
+}
+
+// Bytes returns the hive file with updated header fields.
+func (h *Hive) Bytes() []byte {
+	h.updateHeader()
+	return h.data
+}
+
+// WriteTo writes the hive file with updated header fields to w.
+func (h *Hive) WriteTo(w io.Writer) (int64, error) {
+	n, err := w.Write(h.Bytes())
+	return int64(n), err
+}
+
+// Regffs opens a read-only copy of the current state of the hive.
+func (h *Hive) Regffs() (*Regffs, error) {
+	data := append([]byte{}, h.Bytes()...)
+	return New(bytes.NewReader(data))
+}
+
+// updateHeader increments the sequence numbers and updates the timestamp,
+// the hive bins data size and the checksum. Both sequence numbers are
+// equal, so the hive is not considered dirty.
+func (h *Hive) updateHeader() {
+	sequence := h.uint32(headerPrimarySequence) + 1
+	h.putUint32(headerPrimarySequence, sequence)
+	h.putUint32(headerSecondarySequence, sequence)
+	h.putUint64(headerTimestamp, TimeToFiletime(h.Now()))
+	h.putUint32(headerHiveBinsDataSize, uint32(len(h.data)-hiveBinsOffset))
+	h.putUint32(checksumOffset, HeaderChecksum(h.data))
+}
+
+// HeaderChecksum returns the checksum of the base block b, the XOR of its
+// first 127 DWORDs. The values 0 and 0xffffffff are replaced.
+func HeaderChecksum(b []byte) uint32 {
+	var sum uint32
+	for i := 0; i < checksumOffset; i += 4 {
+		sum ^= binary.LittleEndian.Uint32(b[i:])
+	}
+	switch sum {
+	case 0:
+		return 1
+	case 0xffffffff:
+		return 0xfffffffe
+	}
+	return sum
+}
+
+// TimeToFiletime converts a time to a Windows FILETIME. The zero time
+// results in a zero FILETIME.
+func TimeToFiletime(t time.Time) uint64 {
+	if t.IsZero() {
+		return 0
+	}
+	const unixEpoch = 116444736000000000
+	return uint64(t.Unix()*1e7+int64(t.Nanosecond()/100)) + unixEpoch
+}
+
+// alloc allocates a cell with room for size bytes of data and returns its
+// offset. The cell data is zeroed.
+func (h *Hive) alloc(size int) (uint32, error) {
+	if size < 0 || size > 0x7ffffff0 {
+		return 0, errors.New("cell too large")
+	}
+	need := uint32(size+4+cellAlignment-1) &^ (cellAlignment - 1)
+
+	i := 0
+	for i < len(h.free) && h.free[i].size < need {
+		i++
+	}
+	if i == len(h.free) {
+		h.addBin(need)
+	}
+
+	cell := h.free[i]
+	if rest := cell.size - need; rest >= cellAlignment {
+		h.free[i] = freeCell{cell.offset + need, rest}
+		h.putUint32(hiveBinsOffset+cell.offset+need, rest)
+	} else {
+		need = cell.size
+		h.free = append(h.free[:i], h.free[i+1:]...)
+	}
+	h.putUint32(hiveBinsOffset+cell.offset, uint32(-int32(need)))
+	data := h.data[hiveBinsOffset+cell.offset+4 : hiveBinsOffset+cell.offset+need]
+	for j := range data {
+		data[j] = 0
+	}
+	return cell.offset, nil
+}
+
+// addBin appends a hive bin with a free cell of at least need bytes.
+func (h *Hive) addBin(need uint32) {
+	size := (need + hiveBinHeaderLen + hiveBinAlignment - 1) &^ (hiveBinAlignment - 1)
+	bin := hiveBin{uint32(len(h.data) - hiveBinsOffset), size}
+	h.data = append(h.data, make([]byte, size)...)
+	b := h.data[hiveBinsOffset+bin.offset:]
+	copy(b, "hbin")
+	binary.LittleEndian.PutUint32(b[0x04:], bin.offset)
+	binary.LittleEndian.PutUint32(b[0x08:], bin.size)
+	binary.LittleEndian.PutUint64(b[0x14:], TimeToFiletime(h.Now()))
+	h.bins = append(h.bins, bin)
+
+	cell := freeCell{bin.offset + hiveBinHeaderLen, bin.size - hiveBinHeaderLen}
+	binary.LittleEndian.PutUint32(b[hiveBinHeaderLen:], cell.size)
+	h.free = append(h.free, cell)
+}
+
+// release marks the cell at offset as unallocated and merges it with
+// adjacent unallocated cells of the same bin. The cell data is kept.
+// Offsets of unallocated or invalid cells are ignored.
+func (h *Hive) release(offset uint32) {
+	if h.cell(offset) == nil || int32(h.uint32(hiveBinsOffset+offset)) > 0 {
+		return
+	}
+	size := h.cellSize(offset)
+	h.putUint32(hiveBinsOffset+offset, size)
+	bin := h.binOf(offset)
+
+	i := sort.Search(len(h.free), func(i int) bool { return h.free[i].offset > offset })
+	h.free = append(h.free, freeCell{})
+	copy(h.free[i+1:], h.free[i:])
+	h.free[i] = freeCell{offset, size}
+
+	if next := i + 1; next < len(h.free) && h.free[next].offset == offset+size && h.binOf(h.free[next].offset) == bin {
+		h.free[i].size += h.free[next].size
+		h.free = append(h.free[:next], h.free[next+1:]...)
+	}
+	if prev := i - 1; prev >= 0 && h.free[prev].offset+h.free[prev].size == offset && h.binOf(h.free[prev].offset) == bin {
+		h.free[prev].size += h.free[i].size
+		h.free = append(h.free[:i], h.free[i+1:]...)
+		i = prev
+	}
+	h.putUint32(hiveBinsOffset+h.free[i].offset, h.free[i].size)
+}
+
+func (h *Hive) binOf(offset uint32) int {
+	return sort.Search(len(h.bins), func(i int) bool { return h.bins[i].offset+h.bins[i].size > offset })
+}
+
+// cellSize returns the size of the cell at offset including the size
+// field.
+func (h *Hive) cellSize(offset uint32) uint32 {
+	size := int32(h.uint32(hiveBinsOffset + offset))
+	if size < 0 {
+		size = -size
+	}
+	return uint32(size)
+}
+
+// cell returns the data of the cell at offset, or nil if the offset is
+// outside of the hive bins data.
+func (h *Hive) cell(offset uint32) []byte {
+	start := uint64(hiveBinsOffset) + uint64(offset)
+	if start+4 > uint64(len(h.data)) {
+		return nil
+	}
+	end := start + uint64(h.cellSize(offset))
+	if end > uint64(len(h.data)) || end < start+4 {
+		return nil
+	}
+	return h.data[start+4 : end]
+}
+
+func (h *Hive) uint32(offset uint32) uint32 {
+	return binary.LittleEndian.Uint32(h.data[offset:])
+}
+
+func (h *Hive) putUint32(offset, v uint32) {
+	binary.LittleEndian.PutUint32(h.data[offset:], v)
+}
+
+func (h *Hive) putUint64(offset uint32, v uint64) {
+	binary.LittleEndian.PutUint64(h.data[offset:], v)
+}
+
+// defaultSecurityDescriptor builds a self-relative security descriptor
+// owned by Administrators that grants full access to SYSTEM and
+// Administrators and read access to Everyone.
+func defaultSecurityDescriptor() []byte {
+	const (
+		keyRead        = 0x00020019
+		keyAllAccess   = 0x000f003f
+		aceInheritance = 0x02 // CONTAINER_INHERIT_ACE
+	)
+	administrators := sid(5, 32, 544)
+	system := sid(5, 18)
+	everyone := sid(1, 0)
+
+	var acl []byte
+	aces := []struct {
+		mask uint32
+		sid  []byte
+	}{{keyAllAccess, system}, {keyAllAccess, administrators}, {keyRead, everyone}}
+	for _, ace := range aces {
+		size := 8 + len(ace.sid)
+		acl = append(acl, 0x00, aceInheritance)
+		acl = binary.LittleEndian.AppendUint16(acl, uint16(size))
+		acl = binary.LittleEndian.AppendUint32(acl, ace.mask)
+		acl = append(acl, ace.sid...)
+	}
+	acl = append([]byte{2, 0, 0, 0, byte(len(aces)), 0, 0, 0}, acl...)
+	binary.LittleEndian.PutUint16(acl[2:], uint16(len(acl)))
+
+	const headerSize = 20
+	sd := []byte{1, 0}
+	sd = binary.LittleEndian.AppendUint16(sd, 0x8004) // SE_SELF_RELATIVE | SE_DACL_PRESENT
+	sd = binary.LittleEndian.AppendUint32(sd, headerSize+uint32(len(acl)))
+	sd = binary.LittleEndian.AppendUint32(sd, headerSize+uint32(len(acl)+len(administrators)))
+	sd = binary.LittleEndian.AppendUint32(sd, 0)
+	sd = binary.LittleEndian.AppendUint32(sd, headerSize)
+	sd = append(sd, acl...)
+	sd = append(sd, administrators...)
+	return append(sd, system...)
+}
+
+// sid encodes a binary security identifier with revision 1.
+func sid(authority byte, subAuthorities ...uint32) []byte {
+	b := []byte{1, byte(len(subAuthorities)), 0, 0, 0, 0, 0, authority}
+	for _, subAuthority := range subAuthorities {
+		b = binary.LittleEndian.AppendUint32(b, subAuthority)
+	}
+	return b
+}
+
+// encodeName encodes a key or value name as compressed Latin-1 if possible,
+// otherwise as UTF-16. It returns whether the name is compressed.
+func encodeName(name string) ([]byte, bool) {
+	latin1 := make([]byte, 0, len(name))
+	for _, r := range name {
+		if r > 0xff {
+			return EncodeUTF16(name), false
+		}
+		latin1 = append(latin1, byte(r))
+	}
+	return latin1, true
+}
+
+// normalizeName returns name as it is decoded after encoding.
+func normalizeName(name string) string {
+	return decodeName(encodeName(name))
+}
+
+// decodeName decodes a compressed (Latin-1) or UTF-16 name.
+func decodeName(b []byte, compressed bool) string {
+	if compressed {
+		runes := make([]rune, len(b))
+		for i, c := range b {
+			runes[i] = rune(c)
+		}
+		return string(runes)
+	}
+	u16s := make([]uint16, len(b)/2)
+	for i := range u16s {
+		u16s[i] = binary.LittleEndian.Uint16(b[2*i:])
+	}
+	return string(utf16.Decode(u16s))
+}
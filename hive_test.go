@@ -0,0 +1,270 @@
+package regffs
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHive(t *testing.T) {
+	h, err := NewHive()
+	if err != nil {
+		t.Fatal(err)
+	}
+	modTime := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+	h.Now = func() time.Time { return modTime }
+
+	big := bytes.Repeat([]byte("0123456789abcdef"), 2000)
+	values := []*Value{
+		{Name: "(default)", Type: DataTypeEnum.RegSz, Data: []byte("d\x00\x00\x00")},
+		{Name: "empty", Type: DataTypeEnum.RegNone},
+		{Name: "dword", Type: DataTypeEnum.RegDword, Data: []byte{1, 2, 3, 4}},
+		{Name: "short", Type: DataTypeEnum.RegBinary, Data: []byte{1, 2}},
+		{Name: "qword", Type: DataTypeEnum.RegQword, Data: []byte{1, 2, 3, 4, 5, 6, 7, 8}},
+		{Name: "string", Type: DataTypeEnum.RegSz, Data: []byte("a\x00b\x00c\x00\x00\x00")},
+		{Name: "big", Type: DataTypeEnum.RegBinary, Data: big},
+	}
+	if err := h.CreateKey("Software/Test"); err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range values {
+		if err := h.SetValue("Software/Test", v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := h.SetClassName("Software/Test", "class"); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := h.Regffs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range values {
+		got, err := r.Value("Software/Test/" + want.Name)
+		if err != nil {
+			t.Fatal(want.Name, err)
+		}
+		if got.Type != want.Type || !bytes.Equal(got.Data, want.Data) {
+			t.Errorf("Value(%s) = %d %x, want %d %x", want.Name, got.Type, shorten(got.Data), want.Type, shorten(want.Data))
+		}
+	}
+	className, err := r.ClassName("Software/Test")
+	if err != nil || className != "class" {
+		t.Errorf("ClassName() = %q, %v, want class", className, err)
+	}
+	info, err := fs.Stat(r, "Software/Test")
+	if err != nil || !info.ModTime().Equal(modTime) {
+		t.Errorf("ModTime() = %v, %v, want %v", info.ModTime(), err, modTime)
+	}
+}
+
+func shorten(b []byte) []byte {
+	if len(b) > 16 {
+		return b[:16]
+	}
+	return b
+}
+
+func TestHiveDelete(t *testing.T) {
+	h, err := NewHive()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"a/b/c", "a/d", "e"} {
+		if err := h.CreateKey(key); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{"x", "y", "z"} {
+		if err := h.SetValue("a/b", &Value{Name: name, Type: DataTypeEnum.RegBinary, Data: bytes.Repeat([]byte{1}, 64)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	size := len(h.Bytes())
+
+	if err := h.DeleteValue("A/B", "Y"); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.DeleteKey("a/B"); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.DeleteKey("a/b"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("DeleteKey() = %v, want %v", err, fs.ErrNotExist)
+	}
+	if err := h.DeleteKey("."); err == nil {
+		t.Error("DeleteKey(.) succeeded")
+	}
+
+	r, err := h.Regffs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	err = fs.WalkDir(r, ".", func(p string, d fs.DirEntry, err error) error {
+		paths = append(paths, p)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := strings.Join(paths, ","), ".,a,a/d,e"; got != want {
+		t.Errorf("WalkDir() = %s, want %s", got, want)
+	}
+
+	// freed cells are reused
+	if err := h.CreateKey("a/b/c"); err != nil {
+		t.Fatal(err)
+	}
+	if len(h.Bytes()) != size {
+		t.Errorf("size = %d, want %d", len(h.Bytes()), size)
+	}
+}
+
+func TestHiveSubkeyLists(t *testing.T) {
+	h, err := NewHive()
+	if err != nil {
+		t.Fatal(err)
+	}
+	const n = 1200
+	for i := n - 1; i >= 0; i-- {
+		if err := h.CreateKey(fmt.Sprintf("keys/key%04d", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	nk, err := h.lookup("open", "keys")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ri := h.cell(h.get32(nk, nkSubkeyList))
+	if string(ri[:2]) != "ri" {
+		t.Fatalf("list signature = %s, want ri", ri[:2])
+	}
+	lh := h.cell(binary.LittleEndian.Uint32(ri[4:]))
+	if got, want := binary.LittleEndian.Uint32(lh[8:]), nameHash("key0000"); string(lh[:2]) != "lh" || got != want {
+		t.Errorf("hash = %x, want %x", got, want)
+	}
+
+	r, err := h.Regffs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := fs.ReadDir(r, "keys")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != n {
+		t.Fatalf("ReadDir() = %d entries, want %d", len(entries), n)
+	}
+	for i, entry := range entries {
+		if want := fmt.Sprintf("key%04d", i); entry.Name() != want {
+			t.Fatalf("entry %d = %s, want %s", i, entry.Name(), want)
+		}
+	}
+}
+
+func TestNameHash(t *testing.T) {
+	tests := []struct {
+		name string
+		hash uint32
+	}{
+		{"A", 0x41},
+		{"ab", 0x41*37 + 0x42},
+		{"AB", 0x41*37 + 0x42},
+	}
+	for _, tt := range tests {
+		if got := nameHash(tt.name); got != tt.hash {
+			t.Errorf("nameHash(%s) = %x, want %x", tt.name, got, tt.hash)
+		}
+	}
+}
+
+func TestNameHint(t *testing.T) {
+	// hint of the AppEvents key of NTUSER.DAT
+	if got, want := nameHint("AppEvents"), uint32(0x45707041); got != want {
+		t.Errorf("nameHint() = %x, want %x", got, want)
+	}
+	if got, want := nameHint("ab"), uint32(0x6261); got != want {
+		t.Errorf("nameHint() = %x, want %x", got, want)
+	}
+}
+
+func TestHiveHeader(t *testing.T) {
+	h, err := NewHive()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := h.Bytes()
+	if binary.LittleEndian.Uint32(b[headerPrimarySequence:]) != binary.LittleEndian.Uint32(b[headerSecondarySequence:]) {
+		t.Error("sequence numbers differ")
+	}
+	if got, want := binary.LittleEndian.Uint32(b[checksumOffset:]), HeaderChecksum(b); got != want {
+		t.Errorf("checksum = %x, want %x", got, want)
+	}
+	if got, want := int(binary.LittleEndian.Uint32(b[headerHiveBinsDataSize:])), len(b)-hiveBinsOffset; got != want {
+		t.Errorf("hive bins data size = %d, want %d", got, want)
+	}
+	sequence := binary.LittleEndian.Uint32(b[headerPrimarySequence:])
+	if got := binary.LittleEndian.Uint32(h.Bytes()[headerPrimarySequence:]); got != sequence+1 {
+		t.Errorf("sequence = %d, want %d", got, sequence+1)
+	}
+}
+
+func TestOpenHive(t *testing.T) {
+	f, err := os.Open("testdata/NTUSER.DAT")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	h, err := OpenHive(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := h.SetValue("Control Panel/Desktop", &Value{Name: "Wallpaper", Type: DataTypeEnum.RegSz, Data: []byte("x\x00\x00\x00")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.DeleteKey("Software/Intel"); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.CreateKey("Software/New"); err != nil {
+		t.Fatal(err)
+	}
+
+	// version 1.3 hives use lf lists
+	software, err := h.lookup("open", "Software")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lf := h.cell(h.get32(software, nkSubkeyList)); string(lf[:2]) != "lf" {
+		t.Errorf("list signature = %s, want lf", lf[:2])
+	}
+
+	r, err := h.Regffs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := r.Value("Control Panel/Desktop/Wallpaper")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.String() != "x" {
+		t.Errorf("Wallpaper = %q, want x", v.String())
+	}
+	if _, err := fs.Stat(r, "Software/Intel"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Stat(Software/Intel) = %v, want %v", err, fs.ErrNotExist)
+	}
+	if _, err := fs.Stat(r, "Software/New"); err != nil {
+		t.Error(err)
+	}
+	if _, err := fs.Stat(r, "Software/Microsoft"); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,764 @@
+package regffs
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// Fields of key (nk) cells, relative to the cell data.
+const (
+	nkFlags              = 0x02
+	nkLastWritten        = 0x04
+	nkParent             = 0x10
+	nkSubkeyCount        = 0x14
+	nkSubkeyList         = 0x1c
+	nkVolatileSubkeyList = 0x20
+	nkValueCount         = 0x24
+	nkValueList          = 0x28
+	nkSecurity           = 0x2c
+	nkClassName          = 0x30
+	nkMaxSubkeyName      = 0x34
+	nkMaxSubkeyClass     = 0x38
+	nkMaxValueName       = 0x3c
+	nkMaxValueData       = 0x40
+	nkNameLength         = 0x48
+	nkClassLength        = 0x4a
+	nkName               = 0x4c
+)
+
+// Fields of value (vk) cells, relative to the cell data.
+const (
+	vkNameLength = 0x02
+	vkDataSize   = 0x04
+	vkData       = 0x08
+	vkType       = 0x0c
+	vkFlags      = 0x10
+	vkName       = 0x14
+
+	vkCompName   = 0x0001
+	vkDataInline = 0x80000000
+)
+
+// Fields of security (sk) cells, relative to the cell data.
+const (
+	skFlink          = 0x04
+	skBlink          = 0x08
+	skRefCount       = 0x0c
+	skDescriptorSize = 0x10
+	skDescriptor     = 0x14
+)
+
+const (
+	maxNameLength      = 255
+	maxValueNameLength = 16383
+	// maxListItems is the number of subkeys of a single hash list, larger
+	// lists are split into an ri list of hash lists.
+	maxListItems = 511
+	maxListDepth = 2
+)
+
+// CreateKey creates the key at name and all missing parent keys. New keys
+// inherit the security descriptor of their parent. Existing keys are not
+// modified.
+func (h *Hive) CreateKey(name string) error {
+	elems, err := splitKeyPath("createkey", name)
+	if err != nil {
+		return err
+	}
+	nk := h.rootKey()
+	for _, elem := range elems {
+		if nk, err = h.createKey(nk, elem); err != nil {
+			return &fs.PathError{Op: "createkey", Path: name, Err: err}
+		}
+	}
+	return nil
+}
+
+// createKey returns the subkey name of nk and creates it if it does not
+// exist.
+func (h *Hive) createKey(nk uint32, name string) (uint32, error) {
+	if name == "" || len([]rune(name)) > maxNameLength {
+		return 0, fs.ErrInvalid
+	}
+	if child, ok := h.findSubkey(nk, name); ok {
+		return child, nil
+	}
+	child, err := h.newKey(nk, name, h.get32(nk, nkSecurity))
+	if err != nil {
+		return 0, err
+	}
+	return child, h.setSubkeys(nk, append(h.subkeys(nk), child))
+}
+
+// DeleteKey deletes the key at name with all its subkeys and values. The
+// cells are marked as unallocated, but their data is kept.
+func (h *Hive) DeleteKey(name string) error {
+	nk, err := h.lookup("deletekey", name)
+	if err != nil {
+		return err
+	}
+	if nk == h.rootKey() {
+		return &fs.PathError{Op: "deletekey", Path: name, Err: errors.New("can not delete root key")}
+	}
+	parent := h.get32(nk, nkParent)
+	var subkeys []uint32
+	for _, subkey := range h.subkeys(parent) {
+		if subkey != nk {
+			subkeys = append(subkeys, subkey)
+		}
+	}
+	if err := h.setSubkeys(parent, subkeys); err != nil {
+		return err
+	}
+	h.deleteTree(nk, 0)
+	return nil
+}
+
+// SetValue creates or replaces the value v of the key at key. The names
+// "" and "(default)" refer to the default value of the key.
+func (h *Hive) SetValue(key string, v *Value) error {
+	nk, err := h.lookup("setvalue", key)
+	if err != nil {
+		return err
+	}
+	if err := h.setValue(nk, v); err != nil {
+		return &fs.PathError{Op: "setvalue", Path: key + "/" + v.Name, Err: err}
+	}
+	return nil
+}
+
+func (h *Hive) setValue(nk uint32, v *Value) error {
+	name := v.Name
+	if name == "(default)" {
+		name = ""
+	}
+	if len([]rune(name)) > maxValueNameLength {
+		return errors.New("name too long")
+	}
+	vk, err := h.newValue(v)
+	if err != nil {
+		return err
+	}
+
+	values := h.values(nk)
+	if i, ok := h.findValue(nk, name); ok {
+		h.deleteValue(values[i])
+		values[i] = vk
+	} else {
+		values = append(values, vk)
+	}
+	return h.setValues(nk, values)
+}
+
+// newValue allocates a value cell and its data without adding it to the
+// values of a key.
+func (h *Hive) newValue(v *Value) (uint32, error) {
+	name := v.Name
+	if name == "(default)" {
+		name = ""
+	}
+	encoded, compressed := encodeName(name)
+	size, offset, err := h.storeData(v.Data)
+	if err != nil {
+		return 0, err
+	}
+	vk, err := h.alloc(vkName + len(encoded))
+	if err != nil {
+		return 0, err
+	}
+	c := h.cell(vk)
+	copy(c, "vk")
+	binary.LittleEndian.PutUint16(c[vkNameLength:], uint16(len(encoded)))
+	binary.LittleEndian.PutUint32(c[vkDataSize:], size)
+	binary.LittleEndian.PutUint32(c[vkData:], offset)
+	binary.LittleEndian.PutUint32(c[vkType:], v.Type)
+	if compressed {
+		binary.LittleEndian.PutUint16(c[vkFlags:], vkCompName)
+	}
+	copy(c[vkName:], encoded)
+	return vk, nil
+}
+
+// DeleteValue deletes the value name of the key at key.
+func (h *Hive) DeleteValue(key, name string) error {
+	nk, err := h.lookup("deletevalue", key)
+	if err != nil {
+		return err
+	}
+	if name == "(default)" {
+		name = ""
+	}
+	i, ok := h.findValue(nk, name)
+	if !ok {
+		return &fs.PathError{Op: "deletevalue", Path: key + "/" + name, Err: fs.ErrNotExist}
+	}
+	values := h.values(nk)
+	h.deleteValue(values[i])
+	return h.setValues(nk, append(values[:i], values[i+1:]...))
+}
+
+// SetClassName sets the class name of the key at name. An empty class name
+// removes it.
+func (h *Hive) SetClassName(name, className string) error {
+	nk, err := h.lookup("setclassname", name)
+	if err != nil {
+		return err
+	}
+	return h.setClassName(nk, className)
+}
+
+func (h *Hive) setClassName(nk uint32, className string) error {
+	if offset := h.get32(nk, nkClassName); offset != noCell {
+		h.release(offset)
+	}
+	h.put32(nk, nkClassName, noCell)
+	h.put16(nk, nkClassLength, 0)
+	if className != "" {
+		data := EncodeUTF16(className)
+		offset, err := h.alloc(len(data))
+		if err != nil {
+			return err
+		}
+		copy(h.cell(offset), data)
+		h.put32(nk, nkClassName, offset)
+		h.put16(nk, nkClassLength, uint16(len(data)))
+	}
+	if nk != h.rootKey() {
+		h.updateSubkeyMaxima(h.get32(nk, nkParent))
+	}
+	return nil
+}
+
+// SetModTime sets the last written time of the key at name. Other
+// modifications of a key set it to the current time.
+func (h *Hive) SetModTime(name string, t time.Time) error {
+	nk, err := h.lookup("setmodtime", name)
+	if err != nil {
+		return err
+	}
+	h.put64(nk, nkLastWritten, TimeToFiletime(t))
+	return nil
+}
+
+// splitKeyPath splits a slash separated key path into its names.
+func splitKeyPath(op, name string) ([]string, error) {
+	if name == "." || name == "" {
+		return nil, nil
+	}
+	elems := strings.Split(name, "/")
+	for _, elem := range elems {
+		if elem == "" || len([]rune(elem)) > maxNameLength {
+			return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+		}
+	}
+	return elems, nil
+}
+
+func (h *Hive) lookup(op, name string) (uint32, error) {
+	elems, err := splitKeyPath(op, name)
+	if err != nil {
+		return 0, err
+	}
+	nk := h.rootKey()
+	for _, elem := range elems {
+		child, ok := h.findSubkey(nk, elem)
+		if !ok {
+			return 0, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+		}
+		nk = child
+	}
+	return nk, nil
+}
+
+func (h *Hive) rootKey() uint32 {
+	return h.uint32(headerRootKeyOffset)
+}
+
+// newKey allocates a key cell without adding it to the subkeys of parent.
+func (h *Hive) newKey(parent uint32, name string, sk uint32) (uint32, error) {
+	encoded, compressed := encodeName(name)
+	nk, err := h.alloc(nkName + len(encoded))
+	if err != nil {
+		return 0, err
+	}
+	c := h.cell(nk)
+	copy(c, "nk")
+	if compressed {
+		binary.LittleEndian.PutUint16(c[nkFlags:], NkFlags.KeyCompName)
+	}
+	binary.LittleEndian.PutUint64(c[nkLastWritten:], TimeToFiletime(h.Now()))
+	binary.LittleEndian.PutUint32(c[nkParent:], parent)
+	binary.LittleEndian.PutUint32(c[nkSubkeyList:], noCell)
+	binary.LittleEndian.PutUint32(c[nkVolatileSubkeyList:], noCell)
+	binary.LittleEndian.PutUint32(c[nkValueList:], noCell)
+	binary.LittleEndian.PutUint32(c[nkSecurity:], sk)
+	binary.LittleEndian.PutUint32(c[nkClassName:], noCell)
+	binary.LittleEndian.PutUint16(c[nkNameLength:], uint16(len(encoded)))
+	copy(c[nkName:], encoded)
+	h.put32(sk, skRefCount, h.get32(sk, skRefCount)+1)
+	return nk, nil
+}
+
+func (h *Hive) keyName(nk uint32) string {
+	c := h.cell(nk)
+	if len(c) < nkName {
+		return ""
+	}
+	name := c[nkName:]
+	if n := int(binary.LittleEndian.Uint16(c[nkNameLength:])); n < len(name) {
+		name = name[:n]
+	}
+	return decodeName(name, binary.LittleEndian.Uint16(c[nkFlags:])&NkFlags.KeyCompName != 0)
+}
+
+func (h *Hive) findSubkey(nk uint32, name string) (uint32, bool) {
+	name = normalizeName(name)
+	for _, subkey := range h.subkeys(nk) {
+		if strings.EqualFold(h.keyName(subkey), name) {
+			return subkey, true
+		}
+	}
+	return 0, false
+}
+
+func (h *Hive) subkeys(nk uint32) []uint32 {
+	if h.get32(nk, nkSubkeyCount) == 0 || h.get32(nk, nkSubkeyList) == noCell {
+		return nil
+	}
+	return h.listItems(h.get32(nk, nkSubkeyList), 0)
+}
+
+// listItems returns the key offsets of a lf, lh, li or ri subkey list.
+func (h *Hive) listItems(list uint32, depth int) []uint32 {
+	c := h.cell(list)
+	if len(c) < 4 || depth >= maxListDepth {
+		return nil
+	}
+	count := int(binary.LittleEndian.Uint16(c[2:]))
+	var items []uint32
+	switch string(c[:2]) {
+	case "lf", "lh":
+		for i := 0; i < count && 4+8*i+4 <= len(c); i++ {
+			items = append(items, binary.LittleEndian.Uint32(c[4+8*i:]))
+		}
+	case "li":
+		for i := 0; i < count && 4+4*i+4 <= len(c); i++ {
+			items = append(items, binary.LittleEndian.Uint32(c[4+4*i:]))
+		}
+	case "ri":
+		for i := 0; i < count && 4+4*i+4 <= len(c); i++ {
+			items = append(items, h.listItems(binary.LittleEndian.Uint32(c[4+4*i:]), depth+1)...)
+		}
+	}
+	return items
+}
+
+// setSubkeys replaces the subkey list of nk with a hash list, or an ri list
+// of hash lists, of subkeys sorted by their upper case names. Hives before
+// version 1.5 use lf lists, newer hives lh lists.
+func (h *Hive) setSubkeys(nk uint32, subkeys []uint32) error {
+	if list := h.get32(nk, nkSubkeyList); list != noCell {
+		h.releaseList(list)
+	}
+	h.put32(nk, nkSubkeyList, noCell)
+	h.put32(nk, nkSubkeyCount, 0)
+	h.touch(nk)
+
+	names := map[uint32]string{}
+	for _, subkey := range subkeys {
+		names[subkey] = strings.ToUpper(h.keyName(subkey))
+	}
+	sort.Slice(subkeys, func(i, j int) bool { return names[subkeys[i]] < names[subkeys[j]] })
+
+	var list uint32
+	var err error
+	switch {
+	case len(subkeys) == 0:
+		h.updateSubkeyMaxima(nk)
+		return nil
+	case len(subkeys) <= maxListItems:
+		list, err = h.writeHashList(subkeys)
+	default:
+		var lists []uint32
+		for i := 0; i < len(subkeys); i += maxListItems {
+			end := i + maxListItems
+			if end > len(subkeys) {
+				end = len(subkeys)
+			}
+			lh, err := h.writeHashList(subkeys[i:end])
+			if err != nil {
+				return err
+			}
+			lists = append(lists, lh)
+		}
+		list, err = h.alloc(4 + 4*len(lists))
+		if err == nil {
+			c := h.cell(list)
+			copy(c, "ri")
+			binary.LittleEndian.PutUint16(c[2:], uint16(len(lists)))
+			for i, lh := range lists {
+				binary.LittleEndian.PutUint32(c[4+4*i:], lh)
+			}
+		}
+	}
+	if err != nil {
+		return err
+	}
+	h.put32(nk, nkSubkeyList, list)
+	h.put32(nk, nkSubkeyCount, uint32(len(subkeys)))
+	h.updateSubkeyMaxima(nk)
+	return nil
+}
+
+func (h *Hive) writeHashList(subkeys []uint32) (uint32, error) {
+	lf := h.minorVersion() < 5
+	list, err := h.alloc(4 + 8*len(subkeys))
+	if err != nil {
+		return 0, err
+	}
+	c := h.cell(list)
+	copy(c, "lh")
+	if lf {
+		copy(c, "lf")
+	}
+	binary.LittleEndian.PutUint16(c[2:], uint16(len(subkeys)))
+	for i, subkey := range subkeys {
+		hash := nameHash(h.keyName(subkey))
+		if lf {
+			hash = nameHint(h.keyName(subkey))
+		}
+		binary.LittleEndian.PutUint32(c[4+8*i:], subkey)
+		binary.LittleEndian.PutUint32(c[4+8*i+4:], hash)
+	}
+	return list, nil
+}
+
+func (h *Hive) minorVersion() uint32 {
+	return h.uint32(headerMinorVersion)
+}
+
+// nameHint is the hash of lf list items, the first four characters of the
+// key name.
+func nameHint(name string) uint32 {
+	var hint [4]byte
+	for i, c := range utf16.Encode([]rune(name)) {
+		if i == len(hint) {
+			break
+		}
+		hint[i] = byte(c)
+	}
+	return binary.LittleEndian.Uint32(hint[:])
+}
+
+// nameHash is the hash of lh list items, calculated over the upper case
+// UTF-16 characters of the key name.
+func nameHash(name string) uint32 {
+	var hash uint32
+	for _, c := range utf16.Encode([]rune(strings.ToUpper(name))) {
+		hash = hash*37 + uint32(c)
+	}
+	return hash
+}
+
+// releaseList releases a subkey list, including the lists referenced by an
+// ri list, but not the keys.
+func (h *Hive) releaseList(list uint32) {
+	c := h.cell(list)
+	if len(c) >= 4 && string(c[:2]) == "ri" {
+		count := int(binary.LittleEndian.Uint16(c[2:]))
+		for i := 0; i < count && 4+4*i+4 <= len(c); i++ {
+			h.release(binary.LittleEndian.Uint32(c[4+4*i:]))
+		}
+	}
+	h.release(list)
+}
+
+// updateSubkeyMaxima updates the largest subkey name and class name sizes
+// of nk.
+func (h *Hive) updateSubkeyMaxima(nk uint32) {
+	var maxName, maxClass uint32
+	for _, subkey := range h.subkeys(nk) {
+		if n := uint32(len(utf16.Encode([]rune(h.keyName(subkey))))) * 2; n > maxName {
+			maxName = n
+		}
+		if n := uint32(h.get16(subkey, nkClassLength)); n > maxClass {
+			maxClass = n
+		}
+	}
+	h.put32(nk, nkMaxSubkeyName, maxName)
+	h.put32(nk, nkMaxSubkeyClass, maxClass)
+}
+
+func (h *Hive) deleteTree(nk uint32, depth int) {
+	if depth < maxKeyDepth {
+		for _, subkey := range h.subkeys(nk) {
+			h.deleteTree(subkey, depth+1)
+		}
+	}
+	if list := h.get32(nk, nkSubkeyList); h.get32(nk, nkSubkeyCount) > 0 && list != noCell {
+		h.releaseList(list)
+	}
+	for _, vk := range h.values(nk) {
+		h.deleteValue(vk)
+	}
+	if list := h.get32(nk, nkValueList); h.get32(nk, nkValueCount) > 0 && list != noCell {
+		h.release(list)
+	}
+	if class := h.get32(nk, nkClassName); class != noCell {
+		h.release(class)
+	}
+	h.releaseSecurity(h.get32(nk, nkSecurity))
+	h.release(nk)
+}
+
+// newSecurity allocates a security cell with a reference count of zero
+// and links it into the list of security cells after head. If head is
+// noCell, the cell starts a new list.
+func (h *Hive) newSecurity(sd []byte, head uint32) (uint32, error) {
+	sk, err := h.alloc(skDescriptor + len(sd))
+	if err != nil {
+		return 0, err
+	}
+	c := h.cell(sk)
+	copy(c, "sk")
+	binary.LittleEndian.PutUint32(c[skDescriptorSize:], uint32(len(sd)))
+	copy(c[skDescriptor:], sd)
+	if head == noCell {
+		h.put32(sk, skFlink, sk)
+		h.put32(sk, skBlink, sk)
+		return sk, nil
+	}
+	next := h.get32(head, skFlink)
+	h.put32(sk, skFlink, next)
+	h.put32(sk, skBlink, head)
+	h.put32(next, skBlink, sk)
+	h.put32(head, skFlink, sk)
+	return sk, nil
+}
+
+// releaseSecurity decrements the reference count of a security cell and
+// unlinks and releases it if it is no longer used.
+func (h *Hive) releaseSecurity(sk uint32) {
+	if c := h.cell(sk); len(c) < skDescriptor || string(c[:2]) != "sk" {
+		return
+	}
+	refs := h.get32(sk, skRefCount)
+	if refs > 0 {
+		refs--
+	}
+	h.put32(sk, skRefCount, refs)
+	flink, blink := h.get32(sk, skFlink), h.get32(sk, skBlink)
+	if refs > 0 || flink == sk {
+		return
+	}
+	h.put32(blink, skFlink, flink)
+	h.put32(flink, skBlink, blink)
+	h.release(sk)
+}
+
+func (h *Hive) values(nk uint32) []uint32 {
+	count := h.get32(nk, nkValueCount)
+	if count == 0 || h.get32(nk, nkValueList) == noCell {
+		return nil
+	}
+	list := h.cell(h.get32(nk, nkValueList))
+	var values []uint32
+	for i := 0; i < int(count) && 4*i+4 <= len(list); i++ {
+		values = append(values, binary.LittleEndian.Uint32(list[4*i:]))
+	}
+	return values
+}
+
+func (h *Hive) valueName(vk uint32) string {
+	c := h.cell(vk)
+	if len(c) < vkName {
+		return ""
+	}
+	name := c[vkName:]
+	if n := int(binary.LittleEndian.Uint16(c[vkNameLength:])); n < len(name) {
+		name = name[:n]
+	}
+	return decodeName(name, binary.LittleEndian.Uint16(c[vkFlags:])&vkCompName != 0)
+}
+
+func (h *Hive) findValue(nk uint32, name string) (int, bool) {
+	name = normalizeName(name)
+	for i, vk := range h.values(nk) {
+		if strings.EqualFold(h.valueName(vk), name) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// setValues replaces the value list of nk.
+func (h *Hive) setValues(nk uint32, values []uint32) error {
+	if list := h.get32(nk, nkValueList); list != noCell {
+		h.release(list)
+	}
+	h.put32(nk, nkValueList, noCell)
+	h.put32(nk, nkValueCount, 0)
+	h.touch(nk)
+
+	var maxName, maxData uint32
+	for _, vk := range values {
+		if n := uint32(len(utf16.Encode([]rune(h.valueName(vk))))) * 2; n > maxName {
+			maxName = n
+		}
+		if n := h.get32(vk, vkDataSize) &^ vkDataInline; n > maxData {
+			maxData = n
+		}
+	}
+	h.put32(nk, nkMaxValueName, maxName)
+	h.put32(nk, nkMaxValueData, maxData)
+	if len(values) == 0 {
+		return nil
+	}
+
+	list, err := h.alloc(4 * len(values))
+	if err != nil {
+		return err
+	}
+	c := h.cell(list)
+	for i, vk := range values {
+		binary.LittleEndian.PutUint32(c[4*i:], vk)
+	}
+	h.put32(nk, nkValueList, list)
+	h.put32(nk, nkValueCount, uint32(len(values)))
+	return nil
+}
+
+// storeData stores value data and returns the data size and offset fields
+// of the value cell. Data of up to four bytes is stored in the offset
+// field, larger data in a cell or segmented by a big data (db) cell.
+func (h *Hive) storeData(data []byte) (uint32, uint32, error) {
+	if len(data) <= 4 {
+		inline := make([]byte, 4)
+		copy(inline, data)
+		return uint32(len(data)) | vkDataInline, binary.LittleEndian.Uint32(inline), nil
+	}
+	if len(data) > maxDataSize {
+		return 0, 0, fmt.Errorf("data of %d bytes too large", len(data))
+	}
+	// big data cells are supported since version 1.4
+	if len(data) <= bigDataSegmentSize || h.minorVersion() < 4 {
+		offset, err := h.alloc(len(data))
+		if err != nil {
+			return 0, 0, err
+		}
+		copy(h.cell(offset), data)
+		return uint32(len(data)), offset, nil
+	}
+
+	var segments []uint32
+	for i := 0; i < len(data); i += bigDataSegmentSize {
+		end := i + bigDataSegmentSize
+		if end > len(data) {
+			end = len(data)
+		}
+		segment, err := h.alloc(end - i)
+		if err != nil {
+			return 0, 0, err
+		}
+		copy(h.cell(segment), data[i:end])
+		segments = append(segments, segment)
+	}
+	list, err := h.alloc(4 * len(segments))
+	if err != nil {
+		return 0, 0, err
+	}
+	c := h.cell(list)
+	for i, segment := range segments {
+		binary.LittleEndian.PutUint32(c[4*i:], segment)
+	}
+	db, err := h.alloc(8)
+	if err != nil {
+		return 0, 0, err
+	}
+	c = h.cell(db)
+	copy(c, "db")
+	binary.LittleEndian.PutUint16(c[2:], uint16(len(segments)))
+	binary.LittleEndian.PutUint32(c[4:], list)
+	return uint32(len(data)), db, nil
+}
+
+// deleteValue releases a value cell and its data.
+func (h *Hive) deleteValue(vk uint32) {
+	size, offset := h.get32(vk, vkDataSize), h.get32(vk, vkData)
+	if size&vkDataInline == 0 && size != 0 && offset != noCell {
+		c := h.cell(offset)
+		if size > bigDataSegmentSize && len(c) >= 8 && string(c[:2]) == "db" {
+			count := int(binary.LittleEndian.Uint16(c[2:]))
+			list := binary.LittleEndian.Uint32(c[4:])
+			segments := h.cell(list)
+			for i := 0; i < count && 4*i+4 <= len(segments); i++ {
+				h.release(binary.LittleEndian.Uint32(segments[4*i:]))
+			}
+			h.release(list)
+		}
+		h.release(offset)
+	}
+	h.release(vk)
+}
+
+// touch sets the last written time of nk to the current time.
+func (h *Hive) touch(nk uint32) {
+	h.put64(nk, nkLastWritten, TimeToFiletime(h.Now()))
+}
+
+// EncodeUTF16 encodes s as UTF-16LE without end-of-string character.
+func EncodeUTF16(s string) []byte {
+	var b []byte
+	for _, c := range utf16.Encode([]rune(s)) {
+		b = binary.LittleEndian.AppendUint16(b, c)
+	}
+	return b
+}
+
+// get16, get32, put16, put32 and put64 access the fields of the cell at
+// offset. Accesses outside of the hive bins data are ignored.
+func (h *Hive) get16(offset, field uint32) uint16 {
+	if b := h.field(offset, field, 2); b != nil {
+		return binary.LittleEndian.Uint16(b)
+	}
+	return 0
+}
+
+func (h *Hive) get32(offset, field uint32) uint32 {
+	if b := h.field(offset, field, 4); b != nil {
+		return binary.LittleEndian.Uint32(b)
+	}
+	return noCell
+}
+
+func (h *Hive) put16(offset, field uint32, v uint16) {
+	if b := h.field(offset, field, 2); b != nil {
+		binary.LittleEndian.PutUint16(b, v)
+	}
+}
+
+func (h *Hive) put32(offset, field, v uint32) {
+	if b := h.field(offset, field, 4); b != nil {
+		binary.LittleEndian.PutUint32(b, v)
+	}
+}
+
+func (h *Hive) put64(offset, field uint32, v uint64) {
+	if b := h.field(offset, field, 8); b != nil {
+		binary.LittleEndian.PutUint64(b, v)
+	}
+}
+
+func (h *Hive) field(offset, field, size uint32) []byte {
+	c := h.cell(offset)
+	if uint64(field)+uint64(size) > uint64(len(c)) {
+		return nil
+	}
+	return c[field : field+size]
+}
@@ -0,0 +1,124 @@
+package regffs
+
+import (
+	"path"
+	"time"
+)
+
+// OrphanedKey is an allocated key that is not reachable from the root key,
+// e.g. because it was removed from the subkey list of its parent.
+type OrphanedKey struct {
+	// Offset of the cell, relative to the start of the hive bins data.
+	Offset int64
+	Name   string
+	// Path is reconstructed from the parent key offsets. Parents that can
+	// not be resolved are replaced by "?".
+	Path    string
+	ModTime time.Time
+	Key     *NamedKey
+	// Tree is the orphaned key with its subkeys and values as file system.
+	Tree *Regffs
+}
+
+// OrphanedKeys scans all hive bins for allocated keys that are not
+// reachable from the root key. Orphaned subkeys of orphaned keys are only
+// contained in the Tree of the topmost orphaned key.
+func (r *Regffs) OrphanedKeys() ([]*OrphanedKey, error) {
+	reachable := map[int64]bool{}
+	r.markReachable(int64(r.rootOffset), reachable, 0)
+
+	var keys []*OrphanedKey
+	err := r.walkCells(func(offset int64, cell *HiveBinCell) error {
+		if !cell.IsAllocated() || reachable[offset] {
+			return nil
+		}
+		nk, ok := cell.Data().(*NamedKey)
+		if !ok || !validNamedKey(cell, nk) || nk.Flags()&NkFlags.KeyHiveEntry != 0 {
+			return nil
+		}
+		name := decodeKeyName(nk)
+		keys = append(keys, &OrphanedKey{
+			Offset:  offset,
+			Name:    name,
+			Path:    path.Join(r.parentPath(nk), name),
+			ModTime: FiletimeToTime(nk.LastKeyWrittenDateAndTime().Value()),
+			Key:     nk,
+			Tree:    &Regffs{reader: r.reader, regf: r.regf, header: r.header, rootOffset: uint32(offset), warnings: r.warnings},
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// drop the orphaned keys that are subkeys of other orphaned keys
+	subkeys := map[int64]bool{}
+	for _, key := range keys {
+		for _, offset := range r.subkeyOffsets(key.Key) {
+			if offset != key.Offset {
+				subkeys[offset] = true
+			}
+		}
+	}
+	topmost := keys[:0]
+	for _, key := range keys {
+		if !subkeys[key.Offset] {
+			topmost = append(topmost, key)
+		}
+	}
+	return topmost, nil
+}
+
+// markReachable adds the key at offset and all its subkeys to reachable.
+func (r *Regffs) markReachable(offset int64, reachable map[int64]bool, depth int) {
+	if reachable[offset] || depth > maxKeyDepth {
+		return
+	}
+	reachable[offset] = true
+	cell, err := getCell(offset+hiveBinsOffset, r.reader, r.regf)
+	if err != nil {
+		return
+	}
+	nk, ok := cell.Data().(*NamedKey)
+	if !ok {
+		return
+	}
+	for _, subkey := range r.subkeyOffsets(nk) {
+		r.markReachable(subkey, reachable, depth+1)
+	}
+}
+
+// subkeyOffsets returns the key cell offsets referenced by the subkey list
+// of nk.
+func (r *Regffs) subkeyOffsets(nk *NamedKey) []int64 {
+	if nk.NumberOfSubKeys() == 0 || nk.SubKeysListOffset() == 0xffffffff {
+		return nil
+	}
+	return r.listOffsets(int64(nk.SubKeysListOffset()), 0)
+}
+
+func (r *Regffs) listOffsets(offset int64, depth int) []int64 {
+	if depth >= maxListDepth {
+		return nil
+	}
+	cell, err := getCell(offset+hiveBinsOffset, r.reader, r.regf)
+	if err != nil {
+		return nil
+	}
+	var offsets []int64
+	switch list := cell.Data().(type) {
+	case *SubKeyListRi:
+		for _, item := range list.Items() {
+			offsets = append(offsets, r.listOffsets(int64(item.SubKeyListOffset()), depth+1)...)
+		}
+	case *SubKeyListLhLf:
+		for _, item := range list.Items() {
+			offsets = append(offsets, int64(item.NamedKeyOffset()))
+		}
+	case *SubKeyListLi:
+		for _, item := range list.Items() {
+			offsets = append(offsets, int64(item.NamedKeyOffset()))
+		}
+	}
+	return offsets
+}
@@ -0,0 +1,61 @@
+package regffs
+
+import (
+	"bytes"
+	"os"
+	"testing"
+	"testing/fstest"
+)
+
+func TestOrphanedKeys(t *testing.T) {
+	b, err := os.ReadFile("testdata/NTUSER.DAT")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, err := OpenHive(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	appEvents, err := h.lookup("", "AppEvents")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// unlink AppEvents from the root key without freeing it
+	var subkeys []uint32
+	for _, subkey := range h.subkeys(h.rootKey()) {
+		if subkey != appEvents {
+			subkeys = append(subkeys, subkey)
+		}
+	}
+	if err := h.setSubkeys(h.rootKey(), subkeys); err != nil {
+		t.Fatal(err)
+	}
+
+	fsys, err := New(bytes.NewReader(h.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err := fsys.OrphanedKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 1 {
+		t.Fatalf("OrphanedKeys() = %d keys, want 1", len(keys))
+	}
+	key := keys[0]
+	if key.Offset != int64(appEvents) || key.Name != "AppEvents" || key.Path != "AppEvents" {
+		t.Errorf("OrphanedKeys() = %d %s %s", key.Offset, key.Name, key.Path)
+	}
+	if err := fstest.TestFS(key.Tree, "Schemes/Apps/.Default/SystemNotification"); err != nil {
+		t.Error(err)
+	}
+
+	fsys, err = New(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err = fsys.OrphanedKeys()
+	if err != nil || len(keys) != 0 {
+		t.Errorf("OrphanedKeys() of NTUSER.DAT = %d keys, %v", len(keys), err)
+	}
+}
@@ -0,0 +1,123 @@
+package regffs
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// ErrParentCycle is returned if the parent key offsets of a key form a
+// cycle.
+var ErrParentCycle = errors.New("cycle in parent keys")
+
+// PathOf returns the path of the key or value whose cell contains offset,
+// relative to the start of the hive bins data. The path of a key is
+// resolved by following the parent key offsets up to the root key, which
+// has the path ".". Works for deleted and orphaned keys as well, parents
+// that can not be resolved are replaced by "?". The path of a value is the
+// path of a key whose value list references the value.
+func (r *Regffs) PathOf(offset int64) (string, error) {
+	cell, err := r.CellAt(offset)
+	if err != nil {
+		return "", err
+	}
+	switch cell.Data.(type) {
+	case *NamedKey:
+		p, err := r.keyPath(uint32(cell.Offset))
+		if err != nil {
+			return "", err
+		}
+		if p == "" {
+			return ".", nil
+		}
+		return p, nil
+	case *SubKeyListVk:
+		key, ok := r.valueKey(cell.Offset)
+		if !ok {
+			return "", fmt.Errorf("value at 0x%x: no key references it", cell.Offset)
+		}
+		p, err := r.keyPath(uint32(key))
+		if err != nil {
+			return "", err
+		}
+		return path.Join(p, (&File{cell: cell.cell}).Name()), nil
+	}
+	return "", fmt.Errorf("cell at 0x%x is not a key or value", cell.Offset)
+}
+
+// CellAt returns the cell that contains offset, relative to the start of
+// the hive bins data.
+func (r *Regffs) CellAt(offset int64) (*Cell, error) {
+	it := r.Cells()
+	for it.Next() {
+		cell := it.Cell()
+		if offset >= cell.Offset && offset < cell.Offset+cell.Size {
+			return cell, nil
+		}
+		if bin := it.Bin(); offset >= bin.Offset && offset < bin.Offset+hiveBinHeaderLen {
+			return nil, fmt.Errorf("offset 0x%x is in the header of hive bin 0x%x", offset, bin.Offset)
+		}
+	}
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("offset 0x%x is not in a hive bin", offset)
+}
+
+// keyPath resolves the path of the key at offset by following the parent
+// key offsets up to the root key. The root key itself has the empty path.
+// If a parent can not be resolved, the path starts with "?". On cycles,
+// the path up to the cycle is returned with ErrParentCycle.
+func (r *Regffs) keyPath(offset uint32) (string, error) {
+	var names []string
+	seen := map[uint32]bool{}
+	var err error
+	for depth := 0; depth < maxKeyDepth; depth++ {
+		if seen[offset] {
+			err = ErrParentCycle
+			break
+		}
+		seen[offset] = true
+
+		cell, cellErr := getCell(int64(offset)+hiveBinsOffset, r.reader, r.regf)
+		if cellErr != nil {
+			break
+		}
+		nk, ok := cell.Data().(*NamedKey)
+		if !ok || !validNamedKey(cell, nk) {
+			break
+		}
+		if nk.Flags()&NkFlags.KeyHiveEntry != 0 {
+			reverse(names)
+			return strings.Join(names, "/"), nil
+		}
+		names = append(names, decodeKeyName(nk))
+		offset = nk.ParentKeyOffset()
+	}
+	names = append(names, "?")
+	reverse(names)
+	return strings.Join(names, "/"), err
+}
+
+// valueKey returns the offset of a key whose value list contains the value
+// at offset. Allocated keys are preferred over deleted keys.
+func (r *Regffs) valueKey(offset int64) (int64, bool) {
+	key, found := int64(0), false
+	err := r.walkCells(func(keyOffset int64, cell *HiveBinCell) error {
+		nk, ok := cell.Data().(*NamedKey)
+		if !ok || (found && !cell.IsAllocated()) {
+			return nil
+		}
+		for _, value := range r.valueListOffsets(nk) {
+			if value == offset {
+				key, found = keyOffset, true
+				if cell.IsAllocated() {
+					return errStop
+				}
+			}
+		}
+		return nil
+	})
+	return key, found && (err == nil || errors.Is(err, errStop))
+}
@@ -0,0 +1,73 @@
+package regffs
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestPathOf(t *testing.T) {
+	b, err := os.ReadFile("testdata/NTUSER.DAT")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, err := OpenHive(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	appEvents, err := h.lookup("", "AppEvents")
+	if err != nil {
+		t.Fatal(err)
+	}
+	schemes, err := h.lookup("", "AppEvents/Schemes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	desktop, err := h.lookup("", "Control Panel/Desktop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	i, ok := h.findValue(desktop, "CaretWidth")
+	if !ok {
+		t.Fatal("CaretWidth not found")
+	}
+	caretWidth := h.values(desktop)[i]
+
+	fsys, err := New(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		offset int64
+		want   string
+	}{
+		{int64(fsys.rootOffset), "."},
+		{int64(schemes), "AppEvents/Schemes"},
+		{int64(schemes) + 0x20, "AppEvents/Schemes"},
+		{int64(caretWidth), "Control Panel/Desktop/CaretWidth"},
+	}
+	for _, tt := range tests {
+		got, err := fsys.PathOf(tt.offset)
+		if err != nil || got != tt.want {
+			t.Errorf("PathOf(0x%x) = %q, %v, want %q", tt.offset, got, err, tt.want)
+		}
+	}
+	for _, offset := range []int64{0x10, 0x7fffffff, int64(h.get32(desktop, nkSecurity))} {
+		if got, err := fsys.PathOf(offset); err == nil {
+			t.Errorf("PathOf(0x%x) = %q, want error", offset, got)
+		}
+	}
+
+	// make AppEvents the child of its own subkey
+	cycle := append([]byte(nil), b...)
+	binary.LittleEndian.PutUint32(cycle[hiveBinsOffset+appEvents+4+nkParent:], schemes)
+	fsys, err = New(bytes.NewReader(cycle))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := fsys.PathOf(int64(schemes)); !errors.Is(err, ErrParentCycle) {
+		t.Errorf("PathOf() = %q, %v, want %v", got, err, ErrParentCycle)
+	}
+}
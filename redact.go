@@ -0,0 +1,158 @@
+package regffs
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"path"
+	"regexp"
+)
+
+// RedactRule selects keys and values to redact.
+type RedactRule struct {
+	// Pattern is a path.Match pattern, or a regular expression if Regex
+	// is set. It is matched against the key path, e.g. "Software/*", for
+	// KeyNames and ClassNames and against the value path, e.g.
+	// "Software/Run/Updater", for ValueNames and ValueData. Paths are
+	// relative to the root key and contain the original, decoded names.
+	Pattern string
+	Regex   bool
+
+	KeyNames bool
+	// ClassNames replaces class names by pseudonyms. Class names can
+	// contain key material, e.g. the boot key parts of the Lsa key.
+	ClassNames bool
+	ValueNames bool
+	// ValueData replaces the strings of REG_SZ, REG_EXPAND_SZ, REG_LINK
+	// and REG_MULTI_SZ values by pseudonyms, the data of other types is
+	// zeroed.
+	ValueData bool
+}
+
+// redactPrefix is the prefix of pseudonyms.
+const redactPrefix = "redacted-"
+
+// Redact writes a compacted copy of src to dst in which the key names,
+// class names, value names and value data selected by rules are replaced
+// by pseudonyms. Like Compact, it does not copy unallocated cells, cell
+// slack or deleted keys and values, and it removes the file name of the
+// base block. Pseudonyms are derived from secret by
+// HMAC-SHA256, so equal names and strings get equal pseudonyms in all
+// hives redacted with the same secret. Redact returns the mapping of the
+// original names and strings to their pseudonyms.
+func Redact(dst io.Writer, src *Regffs, secret []byte, rules []RedactRule) (map[string]string, error) {
+	r := &redactor{secret: secret, mapping: map[string]string{}}
+	for _, rule := range rules {
+		pattern := rule.Pattern
+		match := func(name string) bool {
+			ok, _ := path.Match(pattern, name)
+			return ok
+		}
+		if rule.Regex {
+			re, err := regexp.Compile(rule.Pattern)
+			if err != nil {
+				return nil, err
+			}
+			match = re.MatchString
+		} else if _, err := path.Match(rule.Pattern, ""); err != nil {
+			return nil, err
+		}
+		r.rules = append(r.rules, redactRule{RedactRule: rule, match: match})
+	}
+
+	c := newCompactor(src)
+	c.redactor = r
+	if err := c.compact(dst); err != nil {
+		return nil, err
+	}
+	return r.mapping, nil
+}
+
+type redactRule struct {
+	RedactRule
+	match func(name string) bool
+}
+
+type redactor struct {
+	secret  []byte
+	rules   []redactRule
+	mapping map[string]string
+}
+
+func (r *redactor) matches(name string, selected func(rule RedactRule) bool) bool {
+	for _, rule := range r.rules {
+		if selected(rule.RedactRule) && rule.match(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// keyName returns the name of the key at name, which is the pseudonym of
+// keyName if the key is selected.
+func (r *redactor) keyName(name, keyName string) string {
+	if r.matches(name, func(rule RedactRule) bool { return rule.KeyNames }) {
+		return r.pseudonym(keyName)
+	}
+	return keyName
+}
+
+// className returns the class name of the key at name, which is the
+// pseudonym of className if the key is selected.
+func (r *redactor) className(name, className string) string {
+	if r.matches(name, func(rule RedactRule) bool { return rule.ClassNames }) {
+		return r.pseudonym(className)
+	}
+	return className
+}
+
+// value returns the value v at name with redacted name and data if it is
+// selected.
+func (r *redactor) value(name string, v *Value) *Value {
+	redacted := *v
+	if v.Name != "(default)" && r.matches(name, func(rule RedactRule) bool { return rule.ValueNames }) {
+		redacted.Name = r.pseudonym(v.Name)
+	}
+	if r.matches(name, func(rule RedactRule) bool { return rule.ValueData }) {
+		redacted.Data = r.data(v)
+	}
+	return &redacted
+}
+
+func (r *redactor) data(v *Value) []byte {
+	switch v.Type {
+	case DataTypeEnum.RegSz, DataTypeEnum.RegExpandSz, DataTypeEnum.RegLink:
+		s, err := decodeString(v.Data)
+		if err == nil {
+			return append(EncodeUTF16(r.pseudonym(s)), 0, 0)
+		}
+	case DataTypeEnum.RegMultiSz:
+		strs, err := v.Strings()
+		if err == nil {
+			var data []byte
+			for _, s := range strs {
+				data = append(data, EncodeUTF16(r.pseudonym(s))...)
+				data = append(data, 0, 0)
+			}
+			return append(data, 0, 0)
+		}
+	}
+	return make([]byte, len(v.Data))
+}
+
+// pseudonym returns a deterministic replacement for s. Empty strings are
+// kept.
+func (r *redactor) pseudonym(s string) string {
+	if s == "" {
+		return s
+	}
+	if p, ok := r.mapping[s]; ok {
+		return p
+	}
+	mac := hmac.New(sha256.New, r.secret)
+	mac.Write([]byte(s))
+	p := redactPrefix + hex.EncodeToString(mac.Sum(nil)[:6])
+	r.mapping[s] = p
+	return p
+}
@@ -0,0 +1,160 @@
+package regffs
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestRedact(t *testing.T) {
+	fsys := fstest.MapFS{
+		"Users/joe/Name":     {Data: []byte("j\x00o\x00e\x00\x00\x00"), Sys: DataTypeEnum.RegSz},
+		"Users/joe/Secret":   {Data: []byte{1, 2, 3, 4, 5}, Sys: DataTypeEnum.RegBinary},
+		"Users/joe/Hosts":    {Data: []byte("a\x00\x00\x00b\x00\x00\x00\x00\x00"), Sys: DataTypeEnum.RegMultiSz},
+		"Users/joe/Constant": {Data: []byte{1, 0, 0, 0}, Sys: DataTypeEnum.RegDword},
+		"Other/joe":          {Data: []byte{1}},
+	}
+	var built bytes.Buffer
+	if err := Build(&built, fsys); err != nil {
+		t.Fatal(err)
+	}
+	h, err := OpenHive(&built)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the base block file name is the end of the hive path
+	fileName := EncodeUTF16(`\??\C:\Documents and Settings\joe\ntuser.dat`)
+	copy(h.data[headerFileName:headerFileName+64], fileName[len(fileName)-64:])
+	if err := h.SetClassName("Users/joe", "4f8a2b1c"); err != nil {
+		t.Fatal(err)
+	}
+	src, err := h.Regffs()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rules := []RedactRule{
+		{Pattern: "Users/*", KeyNames: true, ClassNames: true},
+		{Pattern: "^Users/[^/]+/(Name|Hosts)$", Regex: true, ValueData: true},
+		{Pattern: "Users/*/Secret", ValueNames: true, ValueData: true},
+	}
+	var buf bytes.Buffer
+	mapping, err := Redact(&buf, src, []byte("secret"), rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dst, err := New(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	joe := mapping["joe"]
+	if !strings.HasPrefix(joe, redactPrefix) {
+		t.Fatalf("mapping[joe] = %q", joe)
+	}
+	if _, err := fs.Stat(dst, "Users/joe"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Stat(Users/joe) = %v, want %v", err, fs.ErrNotExist)
+	}
+	// value names are not matched by the key rule
+	if _, err := fs.Stat(dst, "Other/joe"); err != nil {
+		t.Error(err)
+	}
+
+	name, err := dst.Value("Users/" + joe + "/Name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name.String() != joe {
+		t.Errorf("Name = %q, want %q", name.String(), joe)
+	}
+	hosts, err := dst.Value("Users/" + joe + "/Hosts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := hosts.String(), mapping["a"]+"\n"+mapping["b"]; got != want {
+		t.Errorf("Hosts = %q, want %q", got, want)
+	}
+	secret, err := dst.Value("Users/" + joe + "/" + mapping["Secret"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(secret.Data, make([]byte, 5)) {
+		t.Errorf("Secret = %x, want zeros", secret.Data)
+	}
+	constant, err := dst.Value("Users/" + joe + "/Constant")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(constant.Data, []byte{1, 0, 0, 0}) {
+		t.Errorf("Constant = %x", constant.Data)
+	}
+	if bytes.Contains(buf.Bytes(), []byte("j\x00o\x00e\x00")) {
+		t.Error("redacted hive contains original data")
+	}
+	if fileName := buf.Bytes()[headerFileName : headerFileName+64]; !bytes.Equal(fileName, make([]byte, 64)) {
+		t.Errorf("file name = %q, want none", fileName)
+	}
+	f, err := dst.Open("Users/" + joe)
+	if err != nil {
+		t.Fatal(err)
+	}
+	className, err := f.(*File).ClassName()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := mapping["4f8a2b1c"]; className != want || want == "" {
+		t.Errorf("class name = %q, want %q", className, want)
+	}
+	if bytes.Contains(buf.Bytes(), EncodeUTF16("4f8a2b1c")) {
+		t.Error("redacted hive contains original class name")
+	}
+
+	// pseudonyms are deterministic
+	again, err := Redact(&bytes.Buffer{}, src, []byte("secret"), rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again["joe"] != joe {
+		t.Errorf("pseudonym = %s, want %s", again["joe"], joe)
+	}
+}
+
+func TestRedactUTF16Names(t *testing.T) {
+	var built bytes.Buffer
+	if err := Build(&built, fstest.MapFS{"Users/дом/имя": {Data: []byte{1}}}); err != nil {
+		t.Fatal(err)
+	}
+	src, err := New(bytes.NewReader(built.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rules := []RedactRule{
+		{Pattern: "Users/дом", KeyNames: true},
+		{Pattern: "Users/дом/имя", ValueNames: true},
+	}
+	var buf bytes.Buffer
+	mapping, err := Redact(&buf, src, []byte("secret"), rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, value := mapping["дом"], mapping["имя"]
+	if !strings.HasPrefix(key, redactPrefix) || !strings.HasPrefix(value, redactPrefix) {
+		t.Fatalf("mapping = %q", mapping)
+	}
+	dst, err := New(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fs.Stat(dst, "Users/"+key+"/"+value); err != nil {
+		t.Error(err)
+	}
+	for _, name := range []string{"дом", "имя"} {
+		if bytes.Contains(buf.Bytes(), EncodeUTF16(name)) {
+			t.Errorf("redacted hive contains %s", name)
+		}
+	}
+}
@@ -571,14 +571,12 @@ func (k *SubKeyListVk) Decode(reader io.ReadSeeker, ancestors ...interface{}) (e
 	}
 	if err == nil {
 		var elem []byte
-		if k.Flags() == VkFlags.ValueCompName {
-			elem = make([]byte, k.ValueNameSize())
-			pos, _ := k.decoder.Seek(0, io.SeekCurrent)
-			err = binary.Read(k.decoder, binary.LittleEndian, &elem)
-			pos = pos + int64(k.ValueNameSize())
-			_, err = k.decoder.Seek(pos, io.SeekStart)
-			k.valueName = elem
-		}
+		elem = make([]byte, k.ValueNameSize())
+		pos, _ := k.decoder.Seek(0, io.SeekCurrent)
+		err = binary.Read(k.decoder, binary.LittleEndian, &elem)
+		pos = pos + int64(k.ValueNameSize())
+		_, err = k.decoder.Seek(pos, io.SeekStart)
+		k.valueName = elem
 	}
 	return
 }
@@ -728,7 +726,7 @@ type NamedKey struct {
 	keyNameSize                uint16    `ks:"key_name_size,attribute"`
 	classNameSize              uint16    `ks:"class_name_size,attribute"`
 	unknownStringSize          uint16    `ks:"unknown_string_size,attribute"`
-	unknownStringSize2         uint16    `ks:"unknown_string_size,attribute"`
+	classNameLength            uint16    `ks:"class_name_length,attribute"`
 	unknownString              []byte    `ks:"unknown_string,attribute"`
 }
 
@@ -857,7 +855,7 @@ func (k *NamedKey) Decode(reader io.ReadSeeker, ancestors ...interface{}) (err e
 	if err == nil {
 		var elem uint16
 		err = binary.Read(k.decoder, binary.LittleEndian, &elem)
-		k.unknownStringSize2 = elem
+		k.classNameLength = elem
 	}
 	if err == nil {
 		var elem []byte
@@ -927,6 +925,9 @@ func (k *NamedKey) ClassNameSize() (value uint16) {
 func (k *NamedKey) UnknownStringSize() (value uint16) {
 	return k.unknownStringSize
 }
+func (k *NamedKey) ClassNameLength() (value uint16) {
+	return k.classNameLength
+}
 func (k *NamedKey) UnknownString() (value []byte) {
 	return k.unknownString
 }
@@ -18,7 +18,13 @@ import (
 type Regffs struct {
 	reader io.ReadSeeker
 	regf   *Regf
+	// header is nil if a tolerant Regffs could not decode it.
 	header *FileHeader
+	// rootOffset is the root key offset of the header, or the root key found
+	// in the hive bins by a tolerant Regffs.
+	rootOffset uint32
+	// warnings is only set in tolerant mode.
+	warnings *warnings
 }
 
 func New(f io.ReadSeeker) (*Regffs, error) {
@@ -29,11 +35,11 @@ func New(f io.ReadSeeker) (*Regffs, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &Regffs{f, regf, header}, nil
+	return &Regffs{reader: f, regf: regf, header: header, rootOffset: header.RootKeyOffset()}, nil
 }
 
 func (r *Regffs) Open(name string) (fs.File, error) {
-	offset := r.header.RootKeyOffset() + 0x1000
+	offset := r.rootOffset + 0x1000
 	_, err := r.reader.Seek(int64(offset), io.SeekStart)
 	if err != nil {
 		return nil, err
@@ -45,7 +51,7 @@ func (r *Regffs) Open(name string) (fs.File, error) {
 		return nil, err
 	}
 
-	root := &File{cell: cell, reader: r.reader, regf: r.regf}
+	root := &File{cell: cell, reader: r.reader, regf: r.regf, warnings: r.warnings}
 	if name == "." {
 		return root, nil
 	}
@@ -79,6 +85,8 @@ type File struct {
 	regf      *Regf
 	dirOffset int
 	data      *bytes.Reader
+	// warnings is only set in tolerant mode.
+	warnings *warnings
 }
 
 func (f *File) Size() int64 {
@@ -93,19 +101,26 @@ func (f *File) Mode() fs.FileMode {
 }
 
 func (f *File) ModTime() time.Time {
-	return time.Time{} // TODO
+	if nk, ok := f.cell.Data().(*NamedKey); ok {
+		return FiletimeToTime(nk.LastKeyWrittenDateAndTime().Value())
+	}
+	return time.Time{}
 }
 
+// Sys returns the underlying *NamedKey for keys and *SubKeyListVk for
+// values.
 func (f *File) Sys() interface{} {
-	return nil
+	return f.cell.Data()
 }
 
+// Name returns the decoded key or value name. The default value of a key
+// is named "(default)".
 func (f *File) Name() string {
 	switch k := f.cell.Data().(type) {
 	case *NamedKey:
-		return string(k.UnknownString())
+		return decodeKeyName(k)
 	case *SubKeyListVk:
-		name := string(k.ValueName())
+		name := decodeName(k.ValueName(), k.Flags()&vkCompName != 0)
 		if name == "" {
 			return "(default)"
 		}
@@ -114,6 +129,11 @@ func (f *File) Name() string {
 	return "ERROR"
 }
 
+// decodeKeyName decodes the compressed (Latin-1) or UTF-16 name of nk.
+func decodeKeyName(nk *NamedKey) string {
+	return decodeName(nk.UnknownString(), nk.Flags()&NkFlags.KeyCompName != 0)
+}
+
 func (f *File) IsDir() bool {
 	return string(f.cell.Identifier()) == "nk"
 }
@@ -138,7 +158,7 @@ func (f *File) ReadDir(n int) ([]fs.DirEntry, error) {
 
 	var entries []fs.DirEntry
 	if nk.NumberOfSubKeys() > 0 {
-		entries = f.getSubkeys(int64(nk.SubKeysListOffset()) + 0x1000)
+		entries = f.getSubkeys(int64(nk.SubKeysListOffset())+0x1000, 0)
 	}
 	if nk.NumberOfValues() > 0 {
 		valueEntries, err := f.getValues(nk)
@@ -168,46 +188,64 @@ func (f *File) ReadDir(n int) ([]fs.DirEntry, error) {
 	return entries, nil
 }
 
-func (f *File) getSubkeys(offset int64) []fs.DirEntry {
+// getSubkeys returns the key at offset or the keys of the subkey list at
+// offset. Lists nested deeper than an ri list of hash lists are skipped, as
+// they can only be reached through a corrupted or cyclic list.
+func (f *File) getSubkeys(offset int64, depth int) []fs.DirEntry {
 	cell, err := getCell(offset, f.reader, f.regf)
 	if err != nil {
+		f.warnings.add(offset, "subkey: %v", err)
+		return nil
+	}
+	if _, ok := cell.Data().(*NamedKey); !ok && depth >= maxListDepth {
+		f.warnings.add(offset, "subkey list: too deeply nested")
 		return nil
 	}
 	var entries []fs.DirEntry
 	switch k := cell.Data().(type) {
 	case *SubKeyListRi:
 		for _, item := range k.Items() {
-			entries = append(entries, f.getSubkeys(int64(item.SubKeyListOffset())+0x1000)...)
+			entries = append(entries, f.getSubkeys(int64(item.SubKeyListOffset())+0x1000, depth+1)...)
 		}
 	case *SubKeyListLhLf:
 		for _, item := range k.Items() {
-			entries = append(entries, f.getSubkeys(int64(item.NamedKeyOffset())+0x1000)...)
+			entries = append(entries, f.getSubkeys(int64(item.NamedKeyOffset())+0x1000, depth+1)...)
 		}
 	case *NamedKey:
-		entries = append(entries, &File{reader: f.reader, cell: cell, regf: f.regf})
+		entries = append(entries, &File{reader: f.reader, cell: cell, regf: f.regf, warnings: f.warnings})
+	default:
+		f.warnings.add(offset, "subkey: unexpected %q cell", cell.Identifier())
 	}
 	return entries
 }
 
 func (f *File) getValues(nk *NamedKey) ([]fs.DirEntry, error) {
-	_, err := f.reader.Seek(int64(nk.ValuesListOffset())+0x1000, io.SeekStart)
+	// skip the size of the value list cell
+	offset := int64(nk.ValuesListOffset()) + 0x1000
+	_, err := f.reader.Seek(offset+4, io.SeekStart)
 	if err != nil {
 		return nil, err
 	}
 
-	valueListOffsets := make([]uint32, nk.NumberOfValues()+1)
-	_ = binary.Read(f.reader, binary.LittleEndian, valueListOffsets)
+	list := make([]byte, 4*int64(nk.NumberOfValues()))
+	n, err := io.ReadFull(f.reader, list)
+	if err != nil {
+		f.warnings.add(offset, "value list: %v", err)
+	}
 
 	var entries []fs.DirEntry
-	for _, o := range valueListOffsets {
-		if o == 0xfffffff0 {
+	for i := 0; i+4 <= n; i += 4 {
+		o := int64(binary.LittleEndian.Uint32(list[i:])) + 0x1000
+		cell, err := getCell(o, f.reader, f.regf)
+		if err != nil {
+			f.warnings.add(o, "value: %v", err)
 			continue
 		}
-		cell, err := getCell(int64(o)+0x1000, f.reader, f.regf)
-		if err != nil {
+		if f.warnings != nil && string(cell.Identifier()) != "vk" {
+			f.warnings.add(o, "value: unexpected %q cell", cell.Identifier())
 			continue
 		}
-		entries = append(entries, &File{reader: f.reader, cell: cell, regf: f.regf})
+		entries = append(entries, &File{reader: f.reader, cell: cell, regf: f.regf, warnings: f.warnings})
 	}
 	return entries, nil
 }
@@ -219,11 +257,7 @@ func (f *File) Read(i []byte) (int, error) {
 
 	vk := f.cell.Data().(*SubKeyListVk)
 
-	if vk.DataOffset() == 0 {
-		return 0, io.EOF
-	}
-
-	if vk.DataSize() == 0 {
+	if vk.DataSize() == 0 || vk.DataSize() == 0x80000000 {
 		return 0, io.EOF
 	}
 
@@ -239,33 +273,117 @@ func (f *File) Read(i []byte) (int, error) {
 
 func (f *File) loadData(vk *SubKeyListVk) error {
 	isSet := vk.DataSize()&0x80000000 > 0
-	// dataSize := vk.DataSize()
 	var data []byte
 	if isSet {
-		// dataSize -= 0x80000000
+		// data of up to four bytes is stored in the data offset field
 		data = i32tob(vk.DataOffset())
-		// return copy(i, data), io.EOF
-	} else {
-		_, err := f.reader.Seek(int64(vk.DataOffset())+0x1000+4, io.SeekStart)
+		if size := vk.DataSize() &^ 0x80000000; size < 4 {
+			data = data[:size]
+		}
+	} else if vk.DataSize() > bigDataSegmentSize {
+		d, err := readBigData(f.reader, int64(vk.DataOffset())+0x1000, vk.DataSize())
+		if errors.Is(err, errNoBigData) {
+			// hives before version 1.4 store large data in a single cell
+			d, err = readCellData(f.reader, int64(vk.DataOffset())+0x1000, vk.DataSize())
+		}
 		if err != nil {
 			return err
 		}
-
-		if vk.DataSize() > 16383*2 {
-			return errors.New("entry too large")
-		}
-		d := make([]byte, vk.DataSize())
-		_, err = io.ReadAtLeast(f.reader, d, int(vk.DataSize()))
+		data = d
+	} else {
+		d, err := readCellData(f.reader, int64(vk.DataOffset())+0x1000, vk.DataSize())
 		if err != nil {
 			return err
 		}
-		data = d[:vk.DataSize()]
+		data = d
 	}
 
 	f.data = bytes.NewReader(data)
 	return nil
 }
 
+const (
+	// bigDataSegmentSize is the maximum amount of data in a single cell
+	// since hive version 1.4, larger data is split into segments.
+	bigDataSegmentSize = 16344
+	maxDataSize        = bigDataSegmentSize * 0xffff
+)
+
+var errNoBigData = errors.New("no big data cell")
+
+// readCellData reads size bytes of data from the cell at offset.
+func readCellData(r io.ReadSeeker, offset int64, size uint32) ([]byte, error) {
+	_, err := r.Seek(offset, io.SeekStart)
+	if err != nil {
+		return nil, err
+	}
+	var cellSize int32
+	if err := binary.Read(r, binary.LittleEndian, &cellSize); err != nil {
+		return nil, err
+	}
+	if cellSize < 0 {
+		cellSize = -cellSize
+	}
+	if int64(size) > int64(cellSize)-4 {
+		return nil, errors.New("entry too large")
+	}
+	data := make([]byte, size)
+	_, err = io.ReadFull(r, data)
+	return data, err
+}
+
+// readBigData reads data that is split into segments referenced by a big
+// data ("db") cell at offset.
+func readBigData(r io.ReadSeeker, offset int64, size uint32) ([]byte, error) {
+	if size > maxDataSize {
+		return nil, errors.New("entry too large")
+	}
+	_, err := r.Seek(offset+4, io.SeekStart)
+	if err != nil {
+		return nil, err
+	}
+	var header struct {
+		Identifier [2]byte
+		Count      uint16
+		ListOffset uint32
+	}
+	if err := binary.Read(r, binary.LittleEndian, &header); err != nil {
+		return nil, err
+	}
+	if string(header.Identifier[:]) != "db" {
+		return nil, errNoBigData
+	}
+
+	_, err = r.Seek(int64(header.ListOffset)+0x1000+4, io.SeekStart)
+	if err != nil {
+		return nil, err
+	}
+	segments := make([]uint32, header.Count)
+	if err := binary.Read(r, binary.LittleEndian, segments); err != nil {
+		return nil, err
+	}
+
+	data := make([]byte, 0, size)
+	for _, segment := range segments {
+		n := size - uint32(len(data))
+		if n > bigDataSegmentSize {
+			n = bigDataSegmentSize
+		}
+		if n == 0 {
+			break
+		}
+		d, err := readCellData(r, int64(segment)+0x1000, n)
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, d...)
+	}
+	if uint32(len(data)) != size {
+		return nil, fmt.Errorf("big data has %d of %d bytes", len(data), size)
+	}
+	return data, nil
+}
+
 func (f *File) Close() error {
 	return nil
 }
@@ -297,6 +415,18 @@ func getCell(offset int64, r io.ReadSeeker, regf *Regf) (*HiveBinCell, error) {
 	return cell, nil
 }
 
+// FiletimeToTime converts a Windows FILETIME, the number of 100-nanosecond
+// intervals since January 1, 1601 UTC, to a time.Time. A zero FILETIME
+// results in the zero time.
+func FiletimeToTime(ft uint64) time.Time {
+	if ft == 0 {
+		return time.Time{}
+	}
+	const unixEpoch = 116444736000000000
+	d := int64(ft - unixEpoch)
+	return time.Unix(d/1e7, (d%1e7)*100).UTC()
+}
+
 func DecodeRegSz(b []byte) (string, error) {
 	s, err := DecodeUTF16(b)
 	if err != nil {
@@ -0,0 +1,289 @@
+package sam
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/md5"
+	"crypto/rc4"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path"
+
+	"github.com/forensicanalysis/regffs"
+	"github.com/forensicanalysis/regffs/artifacts"
+)
+
+var (
+	// bootKeyPermutation restores the boot key from the concatenated class
+	// names of the Lsa subkeys.
+	bootKeyPermutation = []int{8, 5, 4, 2, 11, 9, 13, 3, 0, 6, 1, 12, 14, 10, 15, 7}
+	bootKeyParts       = []string{"JD", "Skew1", "GBG", "Data"}
+
+	qwerty = []byte("!@#$%^&*()qwertyUIOPAzxcvbnmQQQQQQQQQQQQ)(*@&%\x00")
+	digits = []byte("0123456789012345678901234567890123456789\x00")
+
+	ntPassword = []byte("NTPASSWORD\x00")
+	lmPassword = []byte("LMPASSWORD\x00")
+)
+
+// Hashes of empty passwords, used when no hash is stored.
+var (
+	EmptyLMHash = mustDecodeHex("aad3b435b51404eeaad3b435b51404ee")
+	EmptyNTHash = mustDecodeHex("31d6cfe0d16ae931b73c59d7e0c089c0")
+)
+
+// Revisions of the encrypted keys and hashes.
+const (
+	revisionRC4 = 1
+	revisionAES = 2
+)
+
+// Hash are the decrypted password hashes of a user. LM and NT are nil if
+// no hash is stored.
+type Hash struct {
+	RID  uint32 `json:"rid"`
+	Name string `json:"name"`
+	LM   []byte `json:"lm,omitempty"`
+	NT   []byte `json:"nt,omitempty"`
+}
+
+// Pwdump formats the hash in the pwdump format, using the hashes of the
+// empty password for missing hashes.
+func (h *Hash) Pwdump() string {
+	lm, nt := h.LM, h.NT
+	if lm == nil {
+		lm = EmptyLMHash
+	}
+	if nt == nil {
+		nt = EmptyNTHash
+	}
+	return fmt.Sprintf("%s:%d:%x:%x:::", h.Name, h.RID, lm, nt)
+}
+
+// Hashes decrypts the password hashes of all users of a SAM hive with the
+// boot key of the SYSTEM hive of the same system.
+func Hashes(samHive, systemHive *regffs.Regffs) ([]*Hash, error) {
+	bootKey, err := BootKey(systemHive)
+	if err != nil {
+		return nil, err
+	}
+	hashedBootKey, err := HashedBootKey(samHive, bootKey)
+	if err != nil {
+		return nil, err
+	}
+	users, err := Users(samHive)
+	if err != nil {
+		return nil, err
+	}
+
+	var hashes []*Hash
+	for _, user := range users {
+		hash, err := user.Hash(hashedBootKey)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", user.Name, err)
+		}
+		hashes = append(hashes, hash)
+	}
+	return hashes, nil
+}
+
+// BootKey derives the boot key (syskey) from the class names of the
+// JD, Skew1, GBG and Data subkeys of Control\Lsa in a SYSTEM hive.
+func BootKey(r *regffs.Regffs) ([]byte, error) {
+	controlSet, err := artifacts.CurrentControlSet(r)
+	if err != nil {
+		return nil, err
+	}
+	var scrambled []byte
+	for _, part := range bootKeyParts {
+		className, err := r.ClassName(path.Join(controlSet, "Control/Lsa", part))
+		if err != nil {
+			return nil, err
+		}
+		b, err := hex.DecodeString(className)
+		if err != nil || len(b) != 4 {
+			return nil, fmt.Errorf("invalid class name %q of %s", className, part)
+		}
+		scrambled = append(scrambled, b...)
+	}
+	return unscrambleBootKey(scrambled), nil
+}
+
+func unscrambleBootKey(scrambled []byte) []byte {
+	bootKey := make([]byte, len(bootKeyPermutation))
+	for i, j := range bootKeyPermutation {
+		bootKey[i] = scrambled[j]
+	}
+	return bootKey
+}
+
+// HashedBootKey decrypts the key of the account domain of a SAM hive that
+// encrypts the password hashes. Older systems use RC4, Windows 10 1607 and
+// later AES.
+func HashedBootKey(r *regffs.Regffs, bootKey []byte) ([]byte, error) {
+	f, err := r.Value(path.Join(accountKey, "F"))
+	if err != nil {
+		return nil, err
+	}
+	return decryptHashedBootKey(f.Data, bootKey)
+}
+
+func decryptHashedBootKey(f, bootKey []byte) ([]byte, error) {
+	const keyOffset = 0x68
+	if len(f) < keyOffset+0x20 {
+		return nil, errors.New("F value too short")
+	}
+	key := f[keyOffset:]
+	switch revision := binary.LittleEndian.Uint32(key); revision {
+	case revisionRC4:
+		// revision, length, salt, key, checksum
+		if len(key) < 0x38 {
+			return nil, errors.New("F value too short")
+		}
+		salt := key[0x08:0x18]
+		decrypted := rc4Crypt(md5Sum(salt, qwerty, bootKey, digits), key[0x18:0x38])
+		hashedBootKey, checksum := decrypted[:16], decrypted[16:]
+		if !bytes.Equal(md5Sum(hashedBootKey, digits, hashedBootKey, qwerty), checksum) {
+			return nil, errors.New("invalid boot key")
+		}
+		return hashedBootKey, nil
+	case revisionAES:
+		// revision, length, checksum length, data length, salt, data
+		dataLength := int(binary.LittleEndian.Uint32(key[0x0c:]))
+		if len(key) < 0x20+dataLength {
+			return nil, errors.New("F value too short")
+		}
+		decrypted, err := aesDecrypt(bootKey, key[0x10:0x20], key[0x20:0x20+dataLength])
+		if err != nil {
+			return nil, err
+		}
+		if len(decrypted) < 16 {
+			return nil, errors.New("hashed boot key too short")
+		}
+		return decrypted[:16], nil
+	default:
+		return nil, fmt.Errorf("unknown hashed boot key revision %d", revision)
+	}
+}
+
+// Hash decrypts the LM and NT hashes of the user with the hashed boot key.
+func (u *User) Hash(hashedBootKey []byte) (*Hash, error) {
+	lm, err := decryptHash(vEntry(u.v, 13), hashedBootKey, u.RID, lmPassword)
+	if err != nil {
+		return nil, fmt.Errorf("LM hash: %w", err)
+	}
+	nt, err := decryptHash(vEntry(u.v, 14), hashedBootKey, u.RID, ntPassword)
+	if err != nil {
+		return nil, fmt.Errorf("NT hash: %w", err)
+	}
+	return &Hash{RID: u.RID, Name: u.Name, LM: lm, NT: nt}, nil
+}
+
+// decryptHash decrypts a hash entry of the V value. Entries start with a
+// PEK ID and the revision, RC4 entries are followed by the hash, AES
+// entries by the data offset, the salt and the hash. Entries without hash
+// return nil.
+func decryptHash(entry, hashedBootKey []byte, rid uint32, constant []byte) ([]byte, error) {
+	if len(entry) < 4 {
+		return nil, nil
+	}
+	var obfuscated []byte
+	switch revision := binary.LittleEndian.Uint16(entry[2:]); revision {
+	case revisionRC4:
+		if len(entry) < 20 {
+			return nil, nil
+		}
+		ridBytes := binary.LittleEndian.AppendUint32(nil, rid)
+		obfuscated = rc4Crypt(md5Sum(hashedBootKey[:16], ridBytes, constant), entry[4:20])
+	case revisionAES:
+		if len(entry) < 24+16 {
+			return nil, nil
+		}
+		decrypted, err := aesDecrypt(hashedBootKey[:16], entry[8:24], entry[24:])
+		if err != nil {
+			return nil, err
+		}
+		obfuscated = decrypted[:16]
+	default:
+		return nil, fmt.Errorf("unknown hash revision %d", revision)
+	}
+	return desDecryptHash(obfuscated, rid)
+}
+
+// desDecryptHash removes the DES obfuscation with keys derived from the
+// RID.
+func desDecryptHash(obfuscated []byte, rid uint32) ([]byte, error) {
+	k1, k2 := desKeys(rid)
+	hash := make([]byte, 16)
+	for i, key := range [][]byte{k1, k2} {
+		block, err := des.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		block.Decrypt(hash[i*8:], obfuscated[i*8:i*8+8])
+	}
+	return hash, nil
+}
+
+func desKeys(rid uint32) ([]byte, []byte) {
+	k := binary.LittleEndian.AppendUint32(nil, rid)
+	return desKey([]byte{k[0], k[1], k[2], k[3], k[0], k[1], k[2]}),
+		desKey([]byte{k[3], k[0], k[1], k[2], k[3], k[0], k[1]})
+}
+
+// desKey expands seven bytes to a DES key by inserting a parity bit after
+// every seven bits. The parity is not set, as it is ignored by DES.
+func desKey(s []byte) []byte {
+	key := []byte{
+		s[0] >> 1,
+		(s[0]&0x01)<<6 | s[1]>>2,
+		(s[1]&0x03)<<5 | s[2]>>3,
+		(s[2]&0x07)<<4 | s[3]>>4,
+		(s[3]&0x0f)<<3 | s[4]>>5,
+		(s[4]&0x1f)<<2 | s[5]>>6,
+		(s[5]&0x3f)<<1 | s[6]>>7,
+		s[6] & 0x7f,
+	}
+	for i := range key {
+		key[i] <<= 1
+	}
+	return key
+}
+
+func md5Sum(parts ...[]byte) []byte {
+	h := md5.New()
+	for _, part := range parts {
+		h.Write(part)
+	}
+	return h.Sum(nil)
+}
+
+func rc4Crypt(key, data []byte) []byte {
+	c, _ := rc4.NewCipher(key)
+	out := make([]byte, len(data))
+	c.XORKeyStream(out, data)
+	return out
+}
+
+func aesDecrypt(key, iv, data []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	data = data[:len(data)&^(aes.BlockSize-1)]
+	out := make([]byte, len(data))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(out, data)
+	return out, nil
+}
+
+func mustDecodeHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
@@ -0,0 +1,225 @@
+package sam
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/forensicanalysis/regffs"
+)
+
+// Known answer vectors of a domain F value of revision 2 with RC4 and of
+// revision 3 with AES encrypted keys, and of a user of each domain. The NT hashes are the MD4 hashes of
+// "password" and "Password1". The F and V values were encrypted with
+// OpenSSL following the documented syskey algorithms, independent of this
+// package.
+var (
+	testBootKey       = mustDecodeHex("e5a3d7c6b1f0928374650a1b2c3d4e5f")
+	testHashedBootKey = mustDecodeHex("3d2c1b0a99887766554433221100ffee")
+)
+
+var testDomains = []struct {
+	name  string
+	f     []byte
+	userF []byte
+	userV []byte
+	want  string
+}{
+	{
+		"RC4",
+		mustDecodeHex(
+			"0200000000000000000000000000000000000000000000000000000000000000" +
+				"0000000000000000000000000000000000000000000000000000000000000000" +
+				"0000000000000000000000000000000000000000000000000000000000000000" +
+				"000000000000000001000000380000006a5b4c3d2e1f00112233445566778899" +
+				"05651770e7feacb4185f26fff7575a043573e62fbcb0073e9ddfe637878f14c3",
+		),
+		mustDecodeHex(
+			"0000000000000000000000000000000000000000000000000000000000000000" +
+				"00000000000000000000000000000000e8030000000000001002000000000000" +
+				"00000000000000000000000000000000",
+		),
+		mustDecodeHex(
+			"000000000000000000000000000000000e000000000000001000000000000000" +
+				"0000000010000000000000000000000010000000000000000000000010000000" +
+				"0000000000000000100000000000000000000000100000000000000000000000" +
+				"1000000000000000000000001000000000000000000000001000000000000000" +
+				"0000000010000000000000000000000010000000000000000000000010000000" +
+				"0400000000000000140000001400000000000000280000000000000000000000" +
+				"280000000000000000000000500072006500730074006f006e00000003000100" +
+				"030001003205f246951fb59d12e1fb2baa9434a8",
+		),
+		"Preston:1000:aad3b435b51404eeaad3b435b51404ee:8846f7eaee8fb117ad06bdd830b7586c:::",
+	},
+	{
+		"AES",
+		mustDecodeHex(
+			"0300000000000000000000000000000000000000000000000000000000000000" +
+				"0000000000000000000000000000000000000000000000000000000000000000" +
+				"0000000000000000000000000000000000000000000000000000000000000000" +
+				"0000000000000000020000004000000010000000200000006a5b4c3d2e1f0011" +
+				"223344556677889972c61afee983af960d306db890e7dde641eeb667afac74a4" +
+				"0c62846b507bd1df",
+		),
+		mustDecodeHex(
+			"0000000000000000000000000000000000000000000000000000000000000000" +
+				"00000000000000000000000000000000e9030000000000001002000000000000" +
+				"00000000000000000000000000000000",
+		),
+		mustDecodeHex(
+			"000000000000000000000000000000000a000000000000000c00000000000000" +
+				"000000000c00000000000000000000000c00000000000000000000000c000000" +
+				"00000000000000000c00000000000000000000000c0000000000000000000000" +
+				"0c00000000000000000000000c00000000000000000000000c00000000000000" +
+				"000000000c00000000000000000000000c00000000000000000000000c000000" +
+				"08000000000000001400000038000000000000004c0000000000000000000000" +
+				"4c00000000000000000000004300610072006f006c0000000300020000000000" +
+				"03000200100000006a5b4c3d2e1f001122334455667788992f3d448cc6a48aa0" +
+				"f2889b8f546dfbe04b6e8e511627a293161c5c865044ceb6",
+		),
+		"Carol:1001:aad3b435b51404eeaad3b435b51404ee:64f12cddaa88057e06a81b54e73b949b:::",
+	},
+}
+
+// testSystemHive builds a SYSTEM hive with the Lsa class names of
+// testBootKey in ControlSet002.
+func testSystemHive(t *testing.T) *regffs.Regffs {
+	t.Helper()
+	h, err := regffs.NewHive()
+	if err != nil {
+		t.Fatal(err)
+	}
+	current := regffs.DwordValue(2)
+	current.Name = "Current"
+	if err := h.CreateKey("Select"); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.SetValue("Select", current); err != nil {
+		t.Fatal(err)
+	}
+	// the scrambled boot key, split into the class names of the subkeys
+	classNames := map[string]string{"JD": "740ac683", "Skew1": "d7a3655f", "GBG": "e5f03db1", "Data": "1b922c4e"}
+	for _, controlSet := range []string{"ControlSet001", "ControlSet002"} {
+		for part, className := range classNames {
+			key := controlSet + "/Control/Lsa/" + part
+			if err := h.CreateKey(key); err != nil {
+				t.Fatal(err)
+			}
+			// other control sets are ignored
+			if controlSet == "ControlSet001" {
+				className = "00000000"
+			}
+			if err := h.SetClassName(key, className); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	r, err := h.Regffs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestBootKey(t *testing.T) {
+	got, err := BootKey(testSystemHive(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, testBootKey) {
+		t.Errorf("BootKey() = %x, want %x", got, testBootKey)
+	}
+}
+
+func TestHashes(t *testing.T) {
+	system := testSystemHive(t)
+	for _, tt := range testDomains {
+		t.Run(tt.name, func(t *testing.T) {
+			h, err := regffs.NewHive()
+			if err != nil {
+				t.Fatal(err)
+			}
+			const user = accountKey + "/Users/000003E8"
+			if err := h.CreateKey(user); err != nil {
+				t.Fatal(err)
+			}
+			for _, value := range []struct {
+				key, name string
+				data      []byte
+			}{
+				{accountKey, "F", tt.f},
+				{user, "F", tt.userF},
+				{user, "V", tt.userV},
+			} {
+				v := &regffs.Value{Name: value.name, Type: regffs.DataTypeEnum.RegBinary, Data: value.data}
+				if err := h.SetValue(value.key, v); err != nil {
+					t.Fatal(err)
+				}
+			}
+			samHive, err := h.Regffs()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			hashes, err := Hashes(samHive, system)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(hashes) != 1 {
+				t.Fatalf("Hashes() = %d hashes, want 1", len(hashes))
+			}
+			if got := hashes[0].Pwdump(); got != tt.want {
+				t.Errorf("Pwdump() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecryptHashedBootKey(t *testing.T) {
+	for _, tt := range testDomains {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decryptHashedBootKey(tt.f, testBootKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, testHashedBootKey) {
+				t.Errorf("decryptHashedBootKey() = %x, want %x", got, testHashedBootKey)
+			}
+		})
+	}
+	if _, err := decryptHashedBootKey(testDomains[0].f, testHashedBootKey); err == nil {
+		t.Error("decryptHashedBootKey() with a wrong boot key succeeded")
+	}
+}
+
+func TestUserHash(t *testing.T) {
+	for _, tt := range testDomains {
+		t.Run(tt.name, func(t *testing.T) {
+			user, err := parseUser(tt.userF, tt.userV)
+			if err != nil {
+				t.Fatal(err)
+			}
+			hash, err := user.Hash(testHashedBootKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if hash.LM != nil {
+				t.Errorf("LM = %x, want none", hash.LM)
+			}
+			if got := hash.Pwdump(); got != tt.want {
+				t.Errorf("Pwdump() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecryptHashEmpty(t *testing.T) {
+	for _, entry := range [][]byte{
+		{0x03, 0x00, 0x01, 0x00},
+		{0x03, 0x00, 0x02, 0x00, 0x00, 0x00, 0x00, 0x00},
+	} {
+		got, err := decryptHash(entry, testHashedBootKey, 1000, ntPassword)
+		if err != nil || got != nil {
+			t.Errorf("decryptHash(%x) = %x, %v, want none", entry, got, err)
+		}
+	}
+}
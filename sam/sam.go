@@ -0,0 +1,344 @@
+// Package sam extracts user accounts and groups from SAM hives opened with
+// regffs.
+package sam
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	"github.com/forensicanalysis/regffs"
+)
+
+const (
+	accountKey = "SAM/Domains/Account"
+	builtinKey = "SAM/Domains/Builtin"
+)
+
+// Account control flags of the F value.
+var AccountFlags = []struct {
+	Flag uint16
+	Name string
+}{
+	{0x0001, "Account Disabled"},
+	{0x0002, "Home Directory Required"},
+	{0x0004, "Password Not Required"},
+	{0x0008, "Temporary Duplicate Account"},
+	{0x0010, "Normal User Account"},
+	{0x0020, "MNS Logon Account"},
+	{0x0040, "Interdomain Trust Account"},
+	{0x0080, "Workstation Trust Account"},
+	{0x0100, "Server Trust Account"},
+	{0x0200, "Password Does Not Expire"},
+	{0x0400, "Account Auto Locked"},
+	{0x0800, "Encrypted Text Password Allowed"},
+	{0x1000, "Smartcard Required"},
+	{0x2000, "Trusted For Delegation"},
+	{0x4000, "Not Delegated"},
+	{0x8000, "Use DES Key Only"},
+}
+
+// User is a local user account.
+type User struct {
+	RID             uint32    `json:"rid"`
+	Name            string    `json:"name"`
+	FullName        string    `json:"full_name,omitempty"`
+	Comment         string    `json:"comment,omitempty"`
+	HomeDir         string    `json:"home_dir,omitempty"`
+	ProfilePath     string    `json:"profile_path,omitempty"`
+	LastLogon       time.Time `json:"last_logon"`
+	PasswordLastSet time.Time `json:"password_last_set"`
+	AccountExpires  time.Time `json:"account_expires"`
+	LastFailedLogon time.Time `json:"last_failed_logon"`
+	LogonCount      uint16    `json:"logon_count"`
+	FailedLogons    uint16    `json:"failed_logons"`
+	Flags           uint16    `json:"flags"`
+	// KeyModTime is the last written time of the user key.
+	KeyModTime time.Time `json:"key_mtime"`
+
+	v []byte
+}
+
+// SID returns the security identifier of the user in the domain.
+func (u *User) SID(domainSID string) string {
+	return fmt.Sprintf("%s-%d", domainSID, u.RID)
+}
+
+// FlagNames returns the names of the account control flags set.
+func (u *User) FlagNames() []string {
+	var names []string
+	for _, flag := range AccountFlags {
+		if u.Flags&flag.Flag != 0 {
+			names = append(names, flag.Name)
+		}
+	}
+	return names
+}
+
+// Group is a local group or alias.
+type Group struct {
+	// Domain is either Builtin or Account.
+	Domain  string `json:"domain"`
+	RID     uint32 `json:"rid"`
+	Name    string `json:"name"`
+	Comment string `json:"comment,omitempty"`
+	// MemberSIDs lists the members of aliases.
+	MemberSIDs []string `json:"member_sids,omitempty"`
+	// MemberRIDs lists the members of groups, which are always in the
+	// account domain.
+	MemberRIDs []uint32 `json:"member_rids,omitempty"`
+	// KeyModTime is the last written time of the group key.
+	KeyModTime time.Time `json:"key_mtime"`
+}
+
+// Users returns the user accounts of a SAM hive.
+func Users(r *regffs.Regffs) ([]*User, error) {
+	usersKey := path.Join(accountKey, "Users")
+	keys, err := ridKeys(r, usersKey)
+	if err != nil {
+		return nil, err
+	}
+
+	var users []*User
+	for _, key := range keys {
+		keyPath := path.Join(usersKey, key.Name())
+		f, err := r.Value(path.Join(keyPath, "F"))
+		if err != nil {
+			return nil, err
+		}
+		v, err := r.Value(path.Join(keyPath, "V"))
+		if err != nil {
+			return nil, err
+		}
+		user, err := parseUser(f.Data, v.Data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", keyPath, err)
+		}
+		if info, err := key.Info(); err == nil {
+			user.KeyModTime = info.ModTime()
+		}
+		users = append(users, user)
+	}
+	return users, nil
+}
+
+func parseUser(f, v []byte) (*User, error) {
+	if len(f) < 0x44 {
+		return nil, errors.New("F value too short")
+	}
+	if len(v) < 0xcc {
+		return nil, errors.New("V value too short")
+	}
+	return &User{
+		RID:             binary.LittleEndian.Uint32(f[0x30:]),
+		Name:            vString(v, 1),
+		FullName:        vString(v, 2),
+		Comment:         vString(v, 3),
+		HomeDir:         vString(v, 6),
+		ProfilePath:     vString(v, 9),
+		LastLogon:       filetime(f[0x08:]),
+		PasswordLastSet: filetime(f[0x18:]),
+		AccountExpires:  filetime(f[0x20:]),
+		LastFailedLogon: filetime(f[0x28:]),
+		Flags:           binary.LittleEndian.Uint16(f[0x38:]),
+		FailedLogons:    binary.LittleEndian.Uint16(f[0x40:]),
+		LogonCount:      binary.LittleEndian.Uint16(f[0x42:]),
+		v:               v,
+	}, nil
+}
+
+// vEntry returns the data of the i-th entry of a V value. The V value
+// starts with a table of offset, length and unknown fields, the offsets are
+// relative to the end of the table.
+func vEntry(v []byte, i int) []byte {
+	const tableSize = 0xcc
+	offset := int(binary.LittleEndian.Uint32(v[i*12:])) + tableSize
+	length := int(binary.LittleEndian.Uint32(v[i*12+4:]))
+	if offset+length > len(v) || length < 0 {
+		return nil
+	}
+	return v[offset : offset+length]
+}
+
+func vString(v []byte, i int) string {
+	return utf16String(vEntry(v, i))
+}
+
+// DomainSID returns the security identifier of the account domain, the
+// prefix of the SIDs of local users, which is stored in the second entry of
+// the V value of the account domain.
+func DomainSID(r *regffs.Regffs) (string, error) {
+	const tableSize = 0x30
+	v, err := r.Value(path.Join(accountKey, "V"))
+	if err != nil {
+		return "", err
+	}
+	if len(v.Data) < tableSize {
+		return "", errors.New("V value too short")
+	}
+	offset := int(binary.LittleEndian.Uint32(v.Data[0x0c:])) + tableSize
+	length := int(binary.LittleEndian.Uint32(v.Data[0x10:]))
+	if offset+length > len(v.Data) {
+		return "", errors.New("V value too short")
+	}
+	sid, _, err := ParseSID(v.Data[offset : offset+length])
+	return sid, err
+}
+
+// Groups returns the aliases of the Builtin and Account domains and the
+// groups of the Account domain.
+func Groups(r *regffs.Regffs) ([]*Group, error) {
+	var groups []*Group
+	for _, domain := range []string{builtinKey, accountKey} {
+		aliases, err := readGroups(r, path.Join(domain, "Aliases"), path.Base(domain), parseAlias)
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, aliases...)
+	}
+	accountGroups, err := readGroups(r, path.Join(accountKey, "Groups"), "Account", parseGroup)
+	if err != nil {
+		return nil, err
+	}
+	return append(groups, accountGroups...), nil
+}
+
+func readGroups(r *regffs.Regffs, key, domain string, parse func([]byte) (*Group, error)) ([]*Group, error) {
+	keys, err := ridKeys(r, key)
+	if err != nil {
+		return nil, err
+	}
+	var groups []*Group
+	for _, k := range keys {
+		keyPath := path.Join(key, k.Name())
+		c, err := r.Value(path.Join(keyPath, "C"))
+		if err != nil {
+			return nil, err
+		}
+		group, err := parse(c.Data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", keyPath, err)
+		}
+		group.Domain = domain
+		if info, err := k.Info(); err == nil {
+			group.KeyModTime = info.ModTime()
+		}
+		groups = append(groups, group)
+	}
+	return groups, nil
+}
+
+// parseAlias decodes the C value of an alias.
+func parseAlias(c []byte) (*Group, error) {
+	const headerSize = 0x34
+	if len(c) < headerSize {
+		return nil, errors.New("C value too short")
+	}
+	group := &Group{
+		RID:     binary.LittleEndian.Uint32(c),
+		Name:    utf16String(cEntry(c, headerSize, 0x10, 0x14)),
+		Comment: utf16String(cEntry(c, headerSize, 0x1c, 0x20)),
+	}
+	members := cEntry(c, headerSize, 0x28, 0x2c)
+	count := int(binary.LittleEndian.Uint32(c[0x30:]))
+	for i := 0; i < count && len(members) >= 8; i++ {
+		sid, n, err := ParseSID(members)
+		if err != nil {
+			return nil, err
+		}
+		group.MemberSIDs = append(group.MemberSIDs, sid)
+		members = members[n:]
+	}
+	return group, nil
+}
+
+// parseGroup decodes the C value of a group.
+func parseGroup(c []byte) (*Group, error) {
+	const headerSize = 0x44
+	if len(c) < headerSize {
+		return nil, errors.New("C value too short")
+	}
+	group := &Group{
+		RID:     binary.LittleEndian.Uint32(c[0x04:]),
+		Name:    utf16String(cEntry(c, headerSize, 0x20, 0x24)),
+		Comment: utf16String(cEntry(c, headerSize, 0x2c, 0x30)),
+	}
+	offset := headerSize + int(binary.LittleEndian.Uint32(c[0x38:]))
+	count := int(binary.LittleEndian.Uint32(c[0x40:]))
+	for i := 0; i < count && offset+4 <= len(c); i, offset = i+1, offset+4 {
+		group.MemberRIDs = append(group.MemberRIDs, binary.LittleEndian.Uint32(c[offset:]))
+	}
+	return group, nil
+}
+
+func cEntry(c []byte, headerSize, offsetField, lengthField int) []byte {
+	offset := headerSize + int(binary.LittleEndian.Uint32(c[offsetField:]))
+	length := int(binary.LittleEndian.Uint32(c[lengthField:]))
+	if offset+length > len(c) || length < 0 {
+		return nil
+	}
+	return c[offset : offset+length]
+}
+
+// ridKeys lists the subkeys of key named by a hexadecimal RID.
+func ridKeys(r *regffs.Regffs, key string) ([]fs.DirEntry, error) {
+	entries, err := fs.ReadDir(r, key)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var keys []fs.DirEntry
+	for _, entry := range entries {
+		if _, err := strconv.ParseUint(entry.Name(), 16, 32); err == nil && entry.IsDir() {
+			keys = append(keys, entry)
+		}
+	}
+	return keys, nil
+}
+
+// ParseSID decodes a binary security identifier and returns it in its
+// string form with the number of bytes read.
+func ParseSID(b []byte) (string, int, error) {
+	if len(b) < 8 {
+		return "", 0, errors.New("SID too short")
+	}
+	count := int(b[1])
+	size := 8 + 4*count
+	if len(b) < size {
+		return "", 0, errors.New("SID too short")
+	}
+	var authority uint64
+	for _, c := range b[2:8] {
+		authority = authority<<8 | uint64(c)
+	}
+	sid := fmt.Sprintf("S-%d-%d", b[0], authority)
+	for i := 0; i < count; i++ {
+		sid += "-" + strconv.FormatUint(uint64(binary.LittleEndian.Uint32(b[8+4*i:])), 10)
+	}
+	return sid, size, nil
+}
+
+// filetime decodes a FILETIME, treating the "never" value as zero time.
+func filetime(b []byte) time.Time {
+	ft := binary.LittleEndian.Uint64(b)
+	if ft == 0x7fffffffffffffff {
+		return time.Time{}
+	}
+	return regffs.FiletimeToTime(ft)
+}
+
+func utf16String(b []byte) string {
+	u16s := make([]uint16, len(b)/2)
+	for i := range u16s {
+		u16s[i] = binary.LittleEndian.Uint16(b[2*i:])
+	}
+	return strings.TrimRight(string(utf16.Decode(u16s)), "\x00")
+}
@@ -0,0 +1,117 @@
+package sam
+
+import (
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/forensicanalysis/regffs"
+)
+
+func openHive(t *testing.T, name string) *regffs.Regffs {
+	t.Helper()
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+
+	fsys, err := regffs.New(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fsys
+}
+
+func TestUsers(t *testing.T) {
+	users, err := Users(openHive(t, "../testdata/SAM"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		rid        uint32
+		name       string
+		logonCount uint16
+		lastLogon  time.Time
+		flags      []string
+	}{
+		{500, "Administrator", 6, time.Date(2010, 11, 20, 21, 48, 12, 0, time.UTC), []string{"Account Disabled", "Normal User Account", "Password Does Not Expire"}},
+		{501, "Guest", 0, time.Time{}, []string{"Account Disabled", "Password Not Required", "Normal User Account", "Password Does Not Expire"}},
+		{1000, "Preston", 4, time.Date(2014, 9, 30, 2, 59, 34, 0, time.UTC), []string{"Normal User Account"}},
+	}
+	if len(users) != len(tests) {
+		t.Fatalf("Users() returned %d users, want %d", len(users), len(tests))
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := users[i]
+			if user.RID != tt.rid || user.Name != tt.name {
+				t.Errorf("user = %d %s, want %d %s", user.RID, user.Name, tt.rid, tt.name)
+			}
+			if user.LogonCount != tt.logonCount {
+				t.Errorf("LogonCount = %d, want %d", user.LogonCount, tt.logonCount)
+			}
+			if !user.LastLogon.Truncate(time.Second).Equal(tt.lastLogon) {
+				t.Errorf("LastLogon = %s, want %s", user.LastLogon, tt.lastLogon)
+			}
+			if !reflect.DeepEqual(user.FlagNames(), tt.flags) {
+				t.Errorf("FlagNames() = %v, want %v", user.FlagNames(), tt.flags)
+			}
+		})
+	}
+}
+
+func TestGroups(t *testing.T) {
+	groups, err := Groups(openHive(t, "../testdata/SAM"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	byName := map[string]*Group{}
+	for _, group := range groups {
+		byName[group.Name] = group
+	}
+
+	administrators, ok := byName["Administrators"]
+	if !ok {
+		t.Fatal("Administrators not found")
+	}
+	wantSIDs := []string{
+		"S-1-5-21-1760460187-1592185332-161725925-500",
+		"S-1-5-21-1760460187-1592185332-161725925-1000",
+	}
+	if administrators.Domain != "Builtin" || administrators.RID != 544 || !reflect.DeepEqual(administrators.MemberSIDs, wantSIDs) {
+		t.Errorf("Administrators = %+v, want Builtin 544 %v", administrators, wantSIDs)
+	}
+
+	none, ok := byName["None"]
+	if !ok {
+		t.Fatal("None not found")
+	}
+	if wantRIDs := []uint32{500, 501, 1000}; none.Domain != "Account" || none.RID != 513 || !reflect.DeepEqual(none.MemberRIDs, wantRIDs) {
+		t.Errorf("None = %+v, want Account 513 %v", none, wantRIDs)
+	}
+}
+
+func TestParseSID(t *testing.T) {
+	b := []byte{1, 2, 0, 0, 0, 0, 0, 5, 32, 0, 0, 0, 32, 2, 0, 0, 0xff}
+	sid, n, err := ParseSID(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sid != "S-1-5-32-544" || n != 16 {
+		t.Errorf("ParseSID() = %s, %d, want S-1-5-32-544, 16", sid, n)
+	}
+}
+
+func TestDomainSID(t *testing.T) {
+	sid, err := DomainSID(openHive(t, "../testdata/SAM"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "S-1-5-21-1760460187-1592185332-161725925"; sid != want {
+		t.Errorf("DomainSID() = %s, want %s", sid, want)
+	}
+}
@@ -0,0 +1,169 @@
+package regffs
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+)
+
+// SearchOptions configure Search. If neither Names nor Data is set, both
+// are searched.
+type SearchOptions struct {
+	// Regex interprets the pattern as regular expression instead of a
+	// literal string.
+	Regex      bool
+	IgnoreCase bool
+	// Names searches key and value names.
+	Names bool
+	// Data searches value data.
+	Data bool
+	// Deleted includes keys and values recovered from unallocated cells,
+	// only supported if the searched fs.FS is a *Regffs.
+	Deleted bool
+	// ValueError is called for values whose data can not be read, so
+	// corrupt values are reported without aborting the search. The values
+	// are skipped silently if it is nil.
+	ValueError func(name string, err error)
+}
+
+// Match is a key or value that matched a search.
+type Match struct {
+	Path    string
+	Key     bool
+	Deleted bool
+	// Field is either "name" or "data".
+	Field string
+	// Text is the matched name, the string representation of matched string
+	// and integer data or the matched part of binary data.
+	Text string
+}
+
+// Search finds keys and values whose name or data matches pattern. Value
+// data is matched in its typed representation, decoded as UTF-16 and as
+// raw bytes. Values whose data can not be read are passed to
+// options.ValueError.
+func Search(fsys fs.FS, pattern string, options SearchOptions) ([]*Match, error) {
+	if !options.Regex {
+		pattern = regexp.QuoteMeta(pattern)
+	}
+	if options.IgnoreCase {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if !options.Names && !options.Data {
+		options.Names, options.Data = true, true
+	}
+
+	var matches []*Match
+	err = fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if name == "." {
+			return nil
+		}
+		if options.Names && re.MatchString(d.Name()) {
+			matches = append(matches, &Match{Path: name, Key: d.IsDir(), Field: "name", Text: d.Name()})
+		}
+		if !options.Data || d.IsDir() {
+			return nil
+		}
+		value, err := readValue(fsys, name, d)
+		if err != nil {
+			if options.ValueError != nil {
+				options.ValueError(name, err)
+			}
+			return nil
+		}
+		if text, ok := matchValue(re, value); ok {
+			matches = append(matches, &Match{Path: name, Field: "data", Text: text})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if r, ok := fsys.(*Regffs); ok && options.Deleted {
+		deletedMatches, err := r.searchDeleted(re, options)
+		if err != nil {
+			return nil, err
+		}
+		matches = append(matches, deletedMatches...)
+	}
+	return matches, nil
+}
+
+func (r *Regffs) searchDeleted(re *regexp.Regexp, options SearchOptions) ([]*Match, error) {
+	var matches []*Match
+	if options.Names {
+		keys, err := r.DeletedKeys()
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range keys {
+			if re.MatchString(key.Name) {
+				matches = append(matches, &Match{Path: key.Path, Key: true, Deleted: true, Field: "name", Text: key.Name})
+			}
+		}
+	}
+
+	values, err := r.DeletedValues()
+	if err != nil {
+		return nil, err
+	}
+	for _, value := range values {
+		if options.Names && re.MatchString(value.Name) {
+			matches = append(matches, &Match{Path: value.Path, Deleted: true, Field: "name", Text: value.Name})
+		}
+		if !options.Data || value.Value == nil {
+			continue
+		}
+		if text, ok := matchValue(re, value.Value); ok {
+			matches = append(matches, &Match{Path: value.Path, Deleted: true, Field: "data", Text: text})
+		}
+	}
+	return matches, nil
+}
+
+// matchValue matches the typed representation of the value, the data
+// decoded as UTF-16 at both byte alignments and the raw data. It returns
+// the full string for string and integer values and only the matched part
+// for binary data.
+func matchValue(re *regexp.Regexp, value *Value) (string, bool) {
+	if i, err := value.Interface(); err == nil {
+		if _, binary := i.([]byte); !binary {
+			if s := value.String(); re.MatchString(s) {
+				return s, true
+			}
+		}
+	}
+	for i := 0; i < 2 && i < len(value.Data); i++ {
+		s, err := DecodeUTF16(evenLength(value.Data[i:]))
+		if err == nil {
+			if loc := re.FindStringIndex(s); loc != nil {
+				return s[loc[0]:loc[1]], true
+			}
+		}
+	}
+	if loc := re.FindIndex(value.Data); loc != nil {
+		return fmt.Sprintf("%q", value.Data[loc[0]:loc[1]]), true
+	}
+	return "", false
+}
+
+// String returns the match in a grep like format.
+func (m *Match) String() string {
+	p := m.Path
+	if m.Key {
+		p = path.Clean(p) + "/"
+	}
+	if m.Deleted {
+		p += " (deleted)"
+	}
+	return p + ": " + m.Text
+}
@@ -0,0 +1,99 @@
+package regffs
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestSearch(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		pattern  string
+		options  SearchOptions
+		expected string
+	}{
+		{"Literal data", "testdata/NTUSER.DAT", "joe", SearchOptions{Data: true}, "Software/Microsoft/Windows/CurrentVersion/Explorer/Logon User Name: joe"},
+		{"Key name", "testdata/NTUSER.DAT", "userassist", SearchOptions{Names: true, IgnoreCase: true}, "Software/Microsoft/Windows/CurrentVersion/Explorer/UserAssist/: UserAssist"},
+		{"UTF-16 in binary", "testdata/SAM", "Backup Op[a-z]+", SearchOptions{Regex: true, Data: true}, "SAM/Domains/Builtin/Aliases/00000227/C: Backup Operators"},
+		{"Deleted key", "testdata/SAM", "Power Users", SearchOptions{Names: true, Deleted: true}, "SAM/Domains/Builtin/Aliases/Names/Power Users/ (deleted): Power Users"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := os.Open(tt.file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			fsys, err := New(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			matches, err := Search(fsys, tt.pattern, tt.options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, match := range matches {
+				if match.String() == tt.expected {
+					return
+				}
+			}
+			t.Errorf("match %q not found in %v", tt.expected, matches)
+		})
+	}
+}
+
+func TestSearchBrokenValue(t *testing.T) {
+	b, err := os.ReadFile("testdata/NTUSER.DAT")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, err := OpenHive(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	desktop, err := h.lookup("", "Control Panel/Desktop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	i, ok := h.findValue(desktop, "Wallpaper")
+	if !ok {
+		t.Fatal("Wallpaper not found")
+	}
+	// point the data of the value outside of the hive
+	vk := h.values(desktop)[i]
+	binary.LittleEndian.PutUint32(b[hiveBinsOffset+vk+4+vkData:], 0x7fff0000)
+
+	fsys, err := New(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var broken []string
+	options := SearchOptions{Data: true, ValueError: func(name string, err error) {
+		broken = append(broken, name)
+	}}
+	matches, err := Search(fsys, "joe", options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var found bool
+	for _, match := range matches {
+		if match.Path == "Control Panel/Desktop/Wallpaper" {
+			t.Errorf("Search() matched the broken value: %v", match)
+		}
+		if match.String() == "Software/Microsoft/Windows/CurrentVersion/Explorer/Logon User Name: joe" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Search() = %v, want the match", matches)
+	}
+	if want := []string{"Control Panel/Desktop/Wallpaper"}; !reflect.DeepEqual(broken, want) {
+		t.Errorf("ValueError() called for %q, want %q", broken, want)
+	}
+}
@@ -0,0 +1,189 @@
+package shellbags
+
+import (
+	"encoding/binary"
+	"errors"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/forensicanalysis/regffs"
+)
+
+// BagMRUKeys are the locations of BagMRU trees in NTUSER.DAT and
+// UsrClass.dat hives.
+var BagMRUKeys = []string{
+	"Software/Microsoft/Windows/Shell/BagMRU",
+	"Software/Microsoft/Windows/ShellNoRoam/BagMRU",
+	"Local Settings/Software/Microsoft/Windows/Shell/BagMRU",
+	"Wow6432Node/Local Settings/Software/Microsoft/Windows/Shell/BagMRU",
+}
+
+// ShellBag is a folder recorded in a BagMRU tree.
+type ShellBag struct {
+	// Path is reconstructed from the shell items of all parent entries.
+	Path string `json:"path"`
+	// Key is the registry path of the BagMRU value.
+	Key string `json:"key"`
+	// Slot is the NodeSlot, the corresponding key in Bags, or -1.
+	Slot int `json:"slot"`
+	// MRUPosition is the position in the MRUListEx of the parent, or -1.
+	MRUPosition int        `json:"mru_position"`
+	Item        *ShellItem `json:"item"`
+	// KeyModTime is the last written time of the BagMRU key of the entry.
+	KeyModTime time.Time `json:"key_mtime,omitempty"`
+}
+
+// ShellBags walks all BagMRU trees of a NTUSER.DAT or UsrClass.dat hive.
+func ShellBags(r *regffs.Regffs) ([]*ShellBag, error) {
+	var bags []*ShellBag
+	for _, name := range BagMRUKeys {
+		f, err := r.Open(name)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		root, ok := f.(*regffs.File)
+		if !ok || !root.IsDir() {
+			continue
+		}
+		bags, err = walk(bags, root, name, "", 0)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return bags, nil
+}
+
+const maxDepth = 128
+
+// bagKey holds the subkeys and values of a BagMRU key, separately as they
+// share their names.
+type bagKey struct {
+	subkeys map[string]*regffs.File
+	values  map[string]*regffs.File
+}
+
+func readBagKey(key *regffs.File) (*bagKey, error) {
+	entries, err := key.ReadDir(-1)
+	if err != nil {
+		return nil, err
+	}
+	k := &bagKey{subkeys: map[string]*regffs.File{}, values: map[string]*regffs.File{}}
+	for _, entry := range entries {
+		f, ok := entry.(*regffs.File)
+		if !ok {
+			continue
+		}
+		if f.IsDir() {
+			k.subkeys[f.Name()] = f
+		} else {
+			k.values[f.Name()] = f
+		}
+	}
+	return k, nil
+}
+
+func (k *bagKey) value(name string) (*regffs.Value, bool) {
+	f, ok := k.values[name]
+	if !ok {
+		return nil, false
+	}
+	v, err := f.Value()
+	return v, err == nil
+}
+
+func walk(bags []*ShellBag, key *regffs.File, keyPath, parentPath string, depth int) ([]*ShellBag, error) {
+	k, err := readBagKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return walkBagKey(bags, k, keyPath, parentPath, depth)
+}
+
+func walkBagKey(bags []*ShellBag, k *bagKey, keyPath, parentPath string, depth int) ([]*ShellBag, error) {
+	if depth > maxDepth {
+		return bags, nil
+	}
+
+	var names []int
+	for name := range k.values {
+		if i, err := strconv.Atoi(name); err == nil {
+			names = append(names, i)
+		}
+	}
+	sort.Ints(names)
+
+	positions := map[int]int{}
+	if v, ok := k.value("MRUListEx"); ok {
+		for i, n := range MRUListEx(v.Data) {
+			positions[n] = i
+		}
+	}
+
+	for _, n := range names {
+		name := strconv.Itoa(n)
+		v, ok := k.value(name)
+		if !ok {
+			continue
+		}
+		items, _ := ParseIDList(v.Data)
+		if len(items) == 0 {
+			continue
+		}
+
+		p := parentPath
+		for _, item := range items {
+			p = joinPath(p, item)
+		}
+		bag := &ShellBag{
+			Path:        p,
+			Key:         path.Join(keyPath, name),
+			Slot:        -1,
+			MRUPosition: -1,
+			Item:        items[len(items)-1],
+		}
+		if pos, ok := positions[n]; ok {
+			bag.MRUPosition = pos
+		}
+		bags = append(bags, bag)
+
+		subkey, ok := k.subkeys[name]
+		if !ok {
+			continue
+		}
+		bag.KeyModTime = subkey.ModTime()
+		child, err := readBagKey(subkey)
+		if err != nil {
+			return nil, err
+		}
+		if v, ok := child.value("NodeSlot"); ok {
+			if slot, err := v.Uint(); err == nil {
+				bag.Slot = int(slot)
+			}
+		}
+		bags, err = walkBagKey(bags, child, bag.Key, p, depth+1)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return bags, nil
+}
+
+// MRUListEx decodes a MRUListEx value, a list of DWORD indices terminated
+// by 0xffffffff.
+func MRUListEx(b []byte) []int {
+	var list []int
+	for i := 0; i+4 <= len(b); i += 4 {
+		n := binary.LittleEndian.Uint32(b[i:])
+		if n == 0xffffffff {
+			break
+		}
+		list = append(list, int(n))
+	}
+	return list
+}
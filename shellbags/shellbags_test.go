@@ -0,0 +1,73 @@
+package shellbags
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/forensicanalysis/regffs"
+)
+
+func TestShellBags(t *testing.T) {
+	f, err := os.Open("../testdata/NTUSER.DAT")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	fsys, err := regffs.New(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bags, err := ShellBags(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		`My Computer`,
+		`My Computer\C:\`,
+		`My Computer\C:\Documents and Settings`,
+		`My Computer\C:\Documents and Settings\Administrator`,
+		`My Computer\C:\Documents and Settings\Administrator\My Documents`,
+	}
+	if len(bags) != len(expected) {
+		t.Fatalf("got %d shellbags, want %d", len(bags), len(expected))
+	}
+	for i, bag := range bags {
+		if bag.Path != expected[i] {
+			t.Errorf("got %q, want %q", bag.Path, expected[i])
+		}
+	}
+
+	last := bags[len(bags)-1]
+	if last.Slot != 5 || last.Item.ShortName != "MYDOCU~1" || !last.Item.Modified.Equal(time.Date(2009, 7, 31, 20, 23, 38, 0, time.UTC)) {
+		t.Errorf("wrong shellbag %+v %+v", last, last.Item)
+	}
+}
+
+func TestParseItem(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     []byte
+		itemType string
+		expected string
+	}{
+		{"Root folder", []byte{0x14, 0x00, 0x1f, 0x50, 0xe0, 0x4f, 0xd0, 0x20, 0xea, 0x3a, 0x69, 0x10, 0xa2, 0xd8, 0x08, 0x00, 0x2b, 0x30, 0x30, 0x9d}, TypeRootFolder, "My Computer"},
+		{"Volume", []byte{0x19, 0x00, 0x2f, 'C', ':', '\\', 0x00}, TypeVolume, `C:\`},
+		{"Network", []byte{0x12, 0x00, 0x41, 0x00, 0x00, '\\', '\\', 's', 'r', 'v', 0x00}, TypeNetworkLocation, `\\srv`},
+		{"URI", []byte{0x12, 0x00, 0x61, 0x00, 0x00, 0x00, 'f', 't', 'p', ':', '/', '/', 'x', 0x00}, TypeURI, "ftp://x"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			item, err := ParseItem(tt.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if item.Type != tt.itemType || item.Name != tt.expected {
+				t.Errorf("got %s %q, want %s %q", item.Type, item.Name, tt.itemType, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,283 @@
+// Package shellbags decodes ShellBags from NTUSER.DAT and UsrClass.dat hives
+// and the shell items (ITEMIDLIST) they are made of.
+package shellbags
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// Shell item types.
+const (
+	TypeRootFolder      = "root folder"
+	TypeVolume          = "volume"
+	TypeDirectory       = "directory"
+	TypeFile            = "file"
+	TypeNetworkLocation = "network location"
+	TypeURI             = "uri"
+	TypeControlPanel    = "control panel"
+	TypeUnknown         = "unknown"
+)
+
+// KnownFolders names the GUIDs of well known shell folders.
+var KnownFolders = map[string]string{
+	"{20D04FE0-3AEA-1069-A2D8-08002B30309D}": "My Computer",
+	"{208D2C60-3AEA-1069-A2D7-08002B30309D}": "My Network Places",
+	"{450D8FBA-AD25-11D0-98A8-0800361B1103}": "My Documents",
+	"{645FF040-5081-101B-9F08-00AA002F954E}": "Recycle Bin",
+	"{21EC2020-3AEA-1069-A2DD-08002B30309D}": "Control Panel",
+	"{26EE0668-A00A-44D7-9371-BEB064C98683}": "Control Panel",
+	"{5399E694-6CE5-4D6C-8FCE-1D8870FDCBA0}": "Control Panel",
+	"{871C5380-42A0-1069-A2EA-08002B30309D}": "Internet Explorer",
+	"{59031A47-3F72-44A7-89C5-5595FE6B30EE}": "Users Files",
+	"{031E4825-7B94-4DC3-B131-E946B44C8DD5}": "Libraries",
+	"{F02C1A0D-BE21-4350-88B0-7367FC96EF3C}": "Network",
+	"{679F85CB-0220-4080-B29B-5540CC05AAB6}": "Quick access",
+	"{B4BFCC3A-DB2C-424C-B029-7FE99A87C641}": "Desktop",
+	"{A8CDFF1C-4878-43BE-B5FD-F8091C1C60D0}": "Documents",
+	"{D3162B92-9365-467A-956B-92703ACA08AF}": "Documents",
+	"{374DE290-123F-4565-9164-39C4925E467B}": "Downloads",
+	"{088E3905-0323-4B02-9826-5D99428E115F}": "Downloads",
+	"{1CF1260C-4DD0-4EBB-811F-33C572699FDE}": "Music",
+	"{3DFDF296-DBEC-4FB4-81D1-6A3438BCF4DE}": "Music",
+	"{3ADD1653-EB32-4CB0-BBD7-DFA0ABB5ACCA}": "Pictures",
+	"{24AD3AD4-A569-4530-98E1-AB02F9417AA8}": "Pictures",
+	"{A0953C92-50DC-43BF-BE83-3742FED03C9C}": "Videos",
+	"{F86FA3AB-70D2-4FC7-9C99-FCBF05467F3A}": "Videos",
+}
+
+// ShellItem is a decoded shell item.
+type ShellItem struct {
+	ClassType byte   `json:"class_type"`
+	Type      string `json:"type"`
+	// Name is the long name if available, otherwise the primary name.
+	Name      string `json:"name"`
+	ShortName string `json:"short_name,omitempty"`
+	GUID      string `json:"guid,omitempty"`
+	Size      uint32 `json:"size,omitempty"`
+	// Timestamps of file entries are stored as FAT date and time in local
+	// time, they are returned as UTC without conversion.
+	Modified    time.Time `json:"modified,omitempty"`
+	Created     time.Time `json:"created,omitempty"`
+	Accessed    time.Time `json:"accessed,omitempty"`
+	MFTEntry    uint64    `json:"mft_entry,omitempty"`
+	MFTSequence uint16    `json:"mft_sequence,omitempty"`
+}
+
+// ParseIDList decodes an ITEMIDLIST, a list of shell items terminated by
+// an item of size 0.
+func ParseIDList(b []byte) ([]*ShellItem, error) {
+	var items []*ShellItem
+	for len(b) >= 2 {
+		size := int(binary.LittleEndian.Uint16(b))
+		if size == 0 {
+			break
+		}
+		if size < 3 || size > len(b) {
+			return items, errors.New("invalid shell item size")
+		}
+		item, err := ParseItem(b[:size])
+		if err != nil {
+			return items, err
+		}
+		items = append(items, item)
+		b = b[size:]
+	}
+	return items, nil
+}
+
+// ParseItem decodes a single shell item including its size field.
+func ParseItem(b []byte) (*ShellItem, error) {
+	if len(b) < 3 {
+		return nil, errors.New("shell item too short")
+	}
+	item := &ShellItem{ClassType: b[2], Type: TypeUnknown}
+	switch {
+	case b[2] == 0x1f || b[2] == 0x2e:
+		item.Type = TypeRootFolder
+		if len(b) >= 20 {
+			item.GUID = formatGUID(b[4:20])
+			item.Name = folderName(item.GUID)
+		}
+	case b[2]&0xf0 == 0x20:
+		item.Type = TypeVolume
+		item.Name = asciiString(b[3:])
+	case b[2]&0xf0 == 0x30:
+		parseFileEntry(item, b)
+	case b[2]&0xf0 == 0x40:
+		item.Type = TypeNetworkLocation
+		if len(b) > 5 {
+			item.Name = asciiString(b[5:])
+		}
+	case b[2] == 0x61:
+		parseURI(item, b)
+	case b[2] == 0x71:
+		item.Type = TypeControlPanel
+		if len(b) >= 30 {
+			item.GUID = formatGUID(b[14:30])
+			item.Name = folderName(item.GUID)
+		}
+	}
+	return item, nil
+}
+
+func parseFileEntry(item *ShellItem, b []byte) {
+	item.Type = TypeFile
+	if b[2]&0x01 != 0 {
+		item.Type = TypeDirectory
+	}
+	if len(b) < 14 {
+		return
+	}
+	item.Size = binary.LittleEndian.Uint32(b[4:])
+	item.Modified = fatTime(b[8:])
+
+	unicode := b[2]&0x04 != 0
+	offset := 14
+	if unicode {
+		item.ShortName, offset = utf16String(b, offset)
+	} else {
+		item.ShortName = asciiString(b[offset:])
+		offset += len(item.ShortName) + 1
+		offset += offset % 2
+	}
+	item.Name = item.ShortName
+
+	// extension blocks
+	for offset+8 <= len(b) {
+		size := int(binary.LittleEndian.Uint16(b[offset:]))
+		if size < 8 || offset+size > len(b) {
+			break
+		}
+		if binary.LittleEndian.Uint32(b[offset+4:]) == 0xbeef0004 {
+			parseFileEntryExtension(item, b[offset:offset+size])
+		}
+		offset += size
+	}
+}
+
+// parseFileEntryExtension decodes the 0xbeef0004 extension block with the
+// long name and further timestamps of a file entry.
+func parseFileEntryExtension(item *ShellItem, b []byte) {
+	if len(b) < 18 {
+		return
+	}
+	version := binary.LittleEndian.Uint16(b[2:])
+	item.Created = fatTime(b[8:])
+	item.Accessed = fatTime(b[12:])
+
+	nameOffset := 0
+	switch {
+	case version >= 9:
+		nameOffset = 46
+	case version == 8:
+		nameOffset = 42
+	case version == 7:
+		nameOffset = 38
+	case version >= 3:
+		nameOffset = 20
+	}
+	if version >= 7 && len(b) >= 28 {
+		ref := binary.LittleEndian.Uint64(b[20:])
+		item.MFTEntry = ref & 0xffffffffffff
+		item.MFTSequence = uint16(ref >> 48)
+	}
+	if nameOffset > 0 && nameOffset < len(b) {
+		if name, _ := utf16String(b, nameOffset); name != "" {
+			item.Name = name
+		}
+	}
+}
+
+func parseURI(item *ShellItem, b []byte) {
+	item.Type = TypeURI
+	if len(b) < 6 {
+		return
+	}
+	flags := b[3]
+	offset := 6 + int(binary.LittleEndian.Uint16(b[4:]))
+	if offset >= len(b) {
+		return
+	}
+	if flags&0x80 != 0 {
+		item.Name, _ = utf16String(b, offset)
+	} else {
+		item.Name = asciiString(b[offset:])
+	}
+}
+
+// JoinPath joins the names of the items to a Windows path.
+func JoinPath(items []*ShellItem) string {
+	var p string
+	for _, item := range items {
+		p = joinPath(p, item)
+	}
+	return p
+}
+
+func joinPath(p string, item *ShellItem) string {
+	name := item.Name
+	if name == "" {
+		name = fmt.Sprintf("<0x%02x>", item.ClassType)
+	}
+	if p == "" {
+		return name
+	}
+	if strings.HasSuffix(p, `\`) {
+		return p + name
+	}
+	return p + `\` + name
+}
+
+func folderName(guid string) string {
+	if name, ok := KnownFolders[guid]; ok {
+		return name
+	}
+	return guid
+}
+
+func formatGUID(b []byte) string {
+	return fmt.Sprintf("{%08X-%04X-%04X-%X-%X}",
+		binary.LittleEndian.Uint32(b[0:]),
+		binary.LittleEndian.Uint16(b[4:]),
+		binary.LittleEndian.Uint16(b[6:]),
+		b[8:10], b[10:16])
+}
+
+// fatTime decodes a FAT date and time, the date being stored first.
+func fatTime(b []byte) time.Time {
+	date, tm := binary.LittleEndian.Uint16(b), binary.LittleEndian.Uint16(b[2:])
+	if date == 0 && tm == 0 {
+		return time.Time{}
+	}
+	return time.Date(
+		int(date>>9)+1980, time.Month(date>>5&0x0f), int(date&0x1f),
+		int(tm>>11), int(tm>>5&0x3f), int(tm&0x1f)*2, 0, time.UTC,
+	)
+}
+
+func asciiString(b []byte) string {
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		b = b[:i]
+	}
+	return string(b)
+}
+
+// utf16String decodes a NUL terminated UTF-16 string at offset and returns
+// it with the offset following the terminator.
+func utf16String(b []byte, offset int) (string, int) {
+	var u16s []uint16
+	for ; offset+1 < len(b); offset += 2 {
+		c := binary.LittleEndian.Uint16(b[offset:])
+		if c == 0 {
+			return string(utf16.Decode(u16s)), offset + 2
+		}
+		u16s = append(u16s, c)
+	}
+	return string(utf16.Decode(u16s)), len(b)
+}
@@ -0,0 +1,219 @@
+package regffs
+
+import (
+	"encoding/binary"
+	"io"
+	"sort"
+	"unicode/utf16"
+)
+
+// RegionType describes why a region of a hive is unused.
+type RegionType string
+
+const (
+	// Free is the data of an unallocated cell.
+	Free RegionType = "free"
+	// Slack is the data of an allocated cell behind its structure.
+	Slack RegionType = "slack"
+)
+
+// Region is unused space of a hive that can contain remnants of older data.
+type Region struct {
+	Type RegionType `json:"type"`
+	// Offset of the region, relative to the start of the hive bins data.
+	Offset int64 `json:"offset"`
+	Size   int64 `json:"size"`
+	// Cell is the offset of the cell that contains the region.
+	Cell int64 `json:"cell"`
+}
+
+// Regions returns the data of unallocated cells and the slack of allocated
+// cells. The used size of a cell is derived from its signature, or from the
+// key, value or big data cell referencing it for value lists, class names
+// and value data. Allocated cells whose use is unknown have no slack.
+func (r *Regffs) Regions() ([]Region, error) {
+	type cellInfo struct {
+		offset    int64
+		size      int64
+		allocated bool
+	}
+	var cells []cellInfo
+	// used is the number of bytes used by the structure of a cell
+	used := map[int64]int64{}
+	// referenced is the number of bytes used by cells without signature
+	referenced := map[int64]int64{}
+
+	err := r.walkCells(func(offset int64, cell *HiveBinCell) error {
+		size := cell.CellSize()
+		cells = append(cells, cellInfo{offset, size, cell.IsAllocated()})
+		if !cell.IsAllocated() {
+			return nil
+		}
+		n := size - 4
+		if n > nkName {
+			n = nkName
+		}
+		data, err := readCellData(r.reader, offset+hiveBinsOffset, uint32(n))
+		if err != nil || len(data) < 4 {
+			return nil
+		}
+		switch string(data[:2]) {
+		case "nk":
+			if len(data) < nkName {
+				return nil
+			}
+			used[offset] = nkName + int64(binary.LittleEndian.Uint16(data[nkNameLength:]))
+			if count := binary.LittleEndian.Uint32(data[nkValueCount:]); count > 0 {
+				referenced[int64(binary.LittleEndian.Uint32(data[nkValueList:]))] = 4 * int64(count)
+			}
+			if length := binary.LittleEndian.Uint16(data[nkClassLength:]); length > 0 {
+				referenced[int64(binary.LittleEndian.Uint32(data[nkClassName:]))] = int64(length)
+			}
+		case "vk":
+			if len(data) < vkName {
+				return nil
+			}
+			used[offset] = vkName + int64(binary.LittleEndian.Uint16(data[vkNameLength:]))
+			dataSize := binary.LittleEndian.Uint32(data[vkDataSize:])
+			if dataSize&vkDataInline == 0 && dataSize > 0 {
+				r.referenceData(int64(binary.LittleEndian.Uint32(data[vkData:])), dataSize, referenced)
+			}
+		case "sk":
+			if len(data) < skDescriptor {
+				return nil
+			}
+			used[offset] = skDescriptor + int64(binary.LittleEndian.Uint32(data[skDescriptorSize:]))
+		case "lf", "lh":
+			used[offset] = 4 + 8*int64(binary.LittleEndian.Uint16(data[2:]))
+		case "li", "ri":
+			used[offset] = 4 + 4*int64(binary.LittleEndian.Uint16(data[2:]))
+		case "db":
+			used[offset] = 8
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var regions []Region
+	for _, cell := range cells {
+		if !cell.allocated {
+			regions = append(regions, Region{Type: Free, Offset: cell.offset + 4, Size: cell.size - 4, Cell: cell.offset})
+			continue
+		}
+		n, ok := referenced[cell.offset]
+		if !ok {
+			n, ok = used[cell.offset]
+		}
+		if ok && n < cell.size-4 {
+			regions = append(regions, Region{Type: Slack, Offset: cell.offset + 4 + n, Size: cell.size - 4 - n, Cell: cell.offset})
+		}
+	}
+	return regions, nil
+}
+
+// referenceData records the bytes used by the data of a value at offset,
+// which is a single cell or a big data cell with segments.
+func (r *Regffs) referenceData(offset int64, size uint32, referenced map[int64]int64) {
+	header, err := readCellData(r.reader, offset+hiveBinsOffset, 8)
+	if err != nil || string(header[:2]) != "db" || size <= bigDataSegmentSize {
+		referenced[offset] = int64(size)
+		return
+	}
+	count := binary.LittleEndian.Uint16(header[2:])
+	list, err := readCellData(r.reader, int64(binary.LittleEndian.Uint32(header[4:]))+hiveBinsOffset, 4*uint32(count))
+	if err != nil {
+		return
+	}
+	referenced[int64(binary.LittleEndian.Uint32(header[4:]))] = 4 * int64(count)
+	for i := 0; i < int(count) && size > 0; i++ {
+		n := size
+		if n > bigDataSegmentSize {
+			n = bigDataSegmentSize
+		}
+		referenced[int64(binary.LittleEndian.Uint32(list[4*i:]))] = int64(n)
+		size -= n
+	}
+}
+
+// ReadRegion reads the data of a region.
+func (r *Regffs) ReadRegion(region Region) ([]byte, error) {
+	if _, err := r.reader.Seek(region.Offset+hiveBinsOffset, io.SeekStart); err != nil {
+		return nil, err
+	}
+	data := make([]byte, region.Size)
+	_, err := io.ReadFull(r.reader, data)
+	return data, err
+}
+
+// RegionString is a string found in a region.
+type RegionString struct {
+	Region Region `json:"region"`
+	// Offset of the string, relative to the start of the hive bins data.
+	Offset   int64  `json:"offset"`
+	Encoding string `json:"encoding"`
+	String   string `json:"string"`
+}
+
+// RegionStrings extracts ASCII and UTF-16LE strings of printable ASCII
+// characters with at least minLength characters from regions.
+func (r *Regffs) RegionStrings(regions []Region, minLength int) ([]RegionString, error) {
+	var strs []RegionString
+	for _, region := range regions {
+		data, err := r.ReadRegion(region)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range extractStrings(data, minLength) {
+			s.Region = region
+			s.Offset += region.Offset
+			strs = append(strs, s)
+		}
+	}
+	return strs, nil
+}
+
+// extractStrings returns the ASCII and UTF-16LE strings of data with
+// offsets relative to data.
+func extractStrings(data []byte, minLength int) []RegionString {
+	if minLength < 1 {
+		minLength = 1
+	}
+	var strs []RegionString
+	start := 0
+	for i := 0; i <= len(data); i++ {
+		if i < len(data) && printable(data[i]) {
+			continue
+		}
+		if i-start >= minLength {
+			strs = append(strs, RegionString{Offset: int64(start), Encoding: "ascii", String: string(data[start:i])})
+		}
+		start = i + 1
+	}
+
+	for parity := 0; parity < 2; parity++ {
+		var units []uint16
+		start := parity
+		for i := parity; ; i += 2 {
+			if i+1 < len(data) && printable(data[i]) && data[i+1] == 0 {
+				units = append(units, uint16(data[i]))
+				continue
+			}
+			if len(units) >= minLength {
+				strs = append(strs, RegionString{Offset: int64(start), Encoding: "utf-16le", String: string(utf16.Decode(units))})
+			}
+			if i+1 >= len(data) {
+				break
+			}
+			units = units[:0]
+			start = i + 2
+		}
+	}
+	sort.SliceStable(strs, func(i, j int) bool { return strs[i].Offset < strs[j].Offset })
+	return strs
+}
+
+func printable(b byte) bool {
+	return b >= 0x20 && b < 0x7f || b == '\t'
+}
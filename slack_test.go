@@ -0,0 +1,112 @@
+package regffs
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestRegions(t *testing.T) {
+	f, err := os.Open("testdata/NTUSER.DAT")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	fsys, err := New(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	regions, err := fsys.Regions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var free, slack int
+	for _, region := range regions {
+		switch region.Type {
+		case Free:
+			free++
+		case Slack:
+			slack++
+		}
+		if region.Size <= 0 || region.Offset < region.Cell+4 {
+			t.Errorf("invalid region %+v", region)
+		}
+	}
+	if free == 0 || slack == 0 {
+		t.Errorf("Regions() = %d free, %d slack", free, slack)
+	}
+
+	strs, err := fsys.RegionStrings(regions, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := RegionString{
+		Region:   Region{Type: Slack, Offset: 0x2932a, Size: 22, Cell: 0x292c8},
+		Offset:   0x2932a,
+		Encoding: "utf-16le",
+		String:   "ion Data",
+	}
+	found := false
+	for _, s := range strs {
+		found = found || s == want
+	}
+	if !found {
+		t.Errorf("RegionStrings() does not contain %+v", want)
+	}
+}
+
+func TestRegionsFree(t *testing.T) {
+	h, err := NewHive()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := h.CreateKey("Key"); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.SetValue("Key", &Value{Name: "Secret", Type: DataTypeEnum.RegSz, Data: append(EncodeUTF16("password123"), 0, 0)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.DeleteValue("Key", "Secret"); err != nil {
+		t.Fatal(err)
+	}
+	fsys, err := New(bytes.NewReader(h.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	regions, err := fsys.Regions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	strs, err := fsys.RegionStrings(regions, 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, s := range strs {
+		if s.Region.Type == Free {
+			got = append(got, s.Encoding+":"+s.String)
+		}
+	}
+	for _, want := range []string{"ascii:Secret", "utf-16le:password123"} {
+		found := false
+		for _, s := range got {
+			found = found || s == want
+		}
+		if !found {
+			t.Errorf("free strings %q do not contain %s", got, want)
+		}
+	}
+}
+
+func TestExtractStrings(t *testing.T) {
+	data := []byte("\x00abc\x01abcdef\x00x\x00y\x00z\x00w\x00\x00\x00abcd")
+	want := []RegionString{
+		{Offset: 5, Encoding: "ascii", String: "abcdef"},
+		{Offset: 10, Encoding: "utf-16le", String: "fxyzw"},
+		{Offset: 22, Encoding: "ascii", String: "abcd"},
+	}
+	if got := extractStrings(data, 4); !reflect.DeepEqual(got, want) {
+		t.Errorf("extractStrings() = %+v, want %+v", got, want)
+	}
+}
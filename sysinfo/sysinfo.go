@@ -0,0 +1,272 @@
+// Package sysinfo summarizes the system profile of SYSTEM and SOFTWARE
+// hives opened with regffs.
+package sysinfo
+
+import (
+	"encoding/binary"
+	"errors"
+	"io/fs"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/forensicanalysis/regffs"
+	"github.com/forensicanalysis/regffs/artifacts"
+)
+
+// Info is the system profile. Fields are empty if the hive is not given or
+// the value is missing.
+type Info struct {
+	// From the SYSTEM hive.
+	ComputerName string       `json:"computer_name,omitempty"`
+	Hostname     string       `json:"hostname,omitempty"`
+	Domain       string       `json:"domain,omitempty"`
+	TimeZone     *TimeZone    `json:"time_zone,omitempty"`
+	LastShutdown time.Time    `json:"last_shutdown"`
+	Interfaces   []*Interface `json:"interfaces,omitempty"`
+
+	// From the SOFTWARE hive.
+	ProductName            string    `json:"product_name,omitempty"`
+	EditionID              string    `json:"edition_id,omitempty"`
+	DisplayVersion         string    `json:"display_version,omitempty"`
+	CurrentVersion         string    `json:"current_version,omitempty"`
+	CurrentBuild           string    `json:"current_build,omitempty"`
+	CSDVersion             string    `json:"csd_version,omitempty"`
+	ProductID              string    `json:"product_id,omitempty"`
+	RegisteredOwner        string    `json:"registered_owner,omitempty"`
+	RegisteredOrganization string    `json:"registered_organization,omitempty"`
+	SystemRoot             string    `json:"system_root,omitempty"`
+	InstallDate            time.Time `json:"install_date"`
+}
+
+// TimeZone is the configured time zone. Biases are in minutes, UTC equals
+// local time plus bias.
+type TimeZone struct {
+	Name           string `json:"name"`
+	StandardName   string `json:"standard_name,omitempty"`
+	DaylightName   string `json:"daylight_name,omitempty"`
+	Bias           int32  `json:"bias"`
+	ActiveTimeBias int32  `json:"active_time_bias"`
+}
+
+// Interface is the TCP/IP configuration of a network interface.
+type Interface struct {
+	GUID           string    `json:"guid"`
+	DHCP           bool      `json:"dhcp"`
+	IPAddresses    []string  `json:"ip_addresses,omitempty"`
+	SubnetMasks    []string  `json:"subnet_masks,omitempty"`
+	DefaultGateway []string  `json:"default_gateway,omitempty"`
+	NameServers    []string  `json:"name_servers,omitempty"`
+	DHCPServer     string    `json:"dhcp_server,omitempty"`
+	Domain         string    `json:"domain,omitempty"`
+	LeaseObtained  time.Time `json:"lease_obtained"`
+	// KeyModTime is the last written time of the interface key.
+	KeyModTime time.Time `json:"key_mtime"`
+}
+
+const currentVersionKey = "Microsoft/Windows NT/CurrentVersion"
+
+// Summary collects the system profile from the given hives. SYSTEM and
+// SOFTWARE hives are detected by their keys, other hives are ignored.
+func Summary(hives ...*regffs.Regffs) (*Info, error) {
+	info := &Info{}
+	for _, r := range hives {
+		if r.Exists("Select") {
+			if err := readSystem(r, info); err != nil {
+				return nil, err
+			}
+		}
+		if r.Exists(currentVersionKey) {
+			if err := readSoftware(r, info); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return info, nil
+}
+
+func readSystem(r *regffs.Regffs, info *Info) error {
+	controlSet, err := artifacts.CurrentControlSet(r)
+	if err != nil {
+		return err
+	}
+
+	computerName, err := r.Values(path.Join(controlSet, "Control/ComputerName/ComputerName"))
+	if err != nil {
+		return err
+	}
+	info.ComputerName = stringValue(computerName, "ComputerName")
+
+	tcpip := path.Join(controlSet, "Services/Tcpip/Parameters")
+	parameters, err := r.Values(tcpip)
+	if err != nil {
+		return err
+	}
+	info.Hostname = stringValue(parameters, "Hostname")
+	info.Domain = stringValue(parameters, "Domain")
+
+	timeZone, err := r.Values(path.Join(controlSet, "Control/TimeZoneInformation"))
+	if err != nil {
+		return err
+	}
+	info.TimeZone = parseTimeZone(timeZone)
+
+	windows, err := r.Values(path.Join(controlSet, "Control/Windows"))
+	if err != nil {
+		return err
+	}
+	if v, ok := windows["shutdowntime"]; ok && len(v.Data) == 8 {
+		info.LastShutdown = regffs.FiletimeToTime(binary.LittleEndian.Uint64(v.Data))
+	}
+
+	interfacesKey := path.Join(tcpip, "Interfaces")
+	interfaces, err := fs.ReadDir(r, interfacesKey)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	for _, entry := range interfaces {
+		if !entry.IsDir() {
+			continue
+		}
+		values, err := r.Values(path.Join(interfacesKey, entry.Name()))
+		if err != nil {
+			return err
+		}
+		iface := parseInterface(entry.Name(), values)
+		if iface == nil {
+			continue
+		}
+		if entryInfo, err := entry.Info(); err == nil {
+			iface.KeyModTime = entryInfo.ModTime()
+		}
+		info.Interfaces = append(info.Interfaces, iface)
+	}
+	return nil
+}
+
+func parseTimeZone(values map[string]*regffs.Value) *TimeZone {
+	if len(values) == 0 {
+		return nil
+	}
+	tz := &TimeZone{
+		Name:           stringValue(values, "TimeZoneKeyName"),
+		StandardName:   stringValue(values, "StandardName"),
+		DaylightName:   stringValue(values, "DaylightName"),
+		Bias:           int32(uintValue(values, "Bias")),
+		ActiveTimeBias: int32(uintValue(values, "ActiveTimeBias")),
+	}
+	if tz.Name == "" {
+		// before Vista only the standard name is stored
+		tz.Name = tz.StandardName
+	}
+	return tz
+}
+
+// parseInterface decodes the values of an interface key. Interfaces
+// without any address are skipped.
+func parseInterface(guid string, values map[string]*regffs.Value) *Interface {
+	iface := &Interface{GUID: guid, DHCP: uintValue(values, "EnableDHCP") == 1}
+	if iface.DHCP {
+		iface.IPAddresses = stringsValue(values, "DhcpIPAddress")
+		iface.SubnetMasks = stringsValue(values, "DhcpSubnetMask")
+		iface.DefaultGateway = stringsValue(values, "DhcpDefaultGateway")
+		iface.NameServers = nameServers(stringValue(values, "DhcpNameServer"))
+		iface.DHCPServer = stringValue(values, "DhcpServer")
+		iface.Domain = stringValue(values, "DhcpDomain")
+		if lease := uintValue(values, "LeaseObtainedTime"); lease != 0 {
+			iface.LeaseObtained = time.Unix(int64(lease), 0).UTC()
+		}
+	} else {
+		iface.IPAddresses = stringsValue(values, "IPAddress")
+		iface.SubnetMasks = stringsValue(values, "SubnetMask")
+		iface.DefaultGateway = stringsValue(values, "DefaultGateway")
+		iface.Domain = stringValue(values, "Domain")
+	}
+	if ns := nameServers(stringValue(values, "NameServer")); len(ns) > 0 {
+		iface.NameServers = ns
+	}
+	iface.IPAddresses = withoutUnset(iface.IPAddresses)
+	if len(iface.IPAddresses) == 0 {
+		return nil
+	}
+	return iface
+}
+
+// nameServers splits a name server list, which is separated by spaces or
+// commas.
+func nameServers(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool { return r == ' ' || r == ',' })
+}
+
+// withoutUnset removes unset addresses, which are stored as 0.0.0.0.
+func withoutUnset(addresses []string) []string {
+	var set []string
+	for _, address := range addresses {
+		if address != "" && address != "0.0.0.0" {
+			set = append(set, address)
+		}
+	}
+	return set
+}
+
+func readSoftware(r *regffs.Regffs, info *Info) error {
+	values, err := r.Values(currentVersionKey)
+	if err != nil {
+		return err
+	}
+	info.ProductName = stringValue(values, "ProductName")
+	info.EditionID = stringValue(values, "EditionID")
+	info.DisplayVersion = stringValue(values, "DisplayVersion")
+	if info.DisplayVersion == "" {
+		info.DisplayVersion = stringValue(values, "ReleaseId")
+	}
+	info.CurrentVersion = stringValue(values, "CurrentVersion")
+	info.CurrentBuild = stringValue(values, "CurrentBuild")
+	if info.CurrentBuild == "" {
+		info.CurrentBuild = stringValue(values, "CurrentBuildNumber")
+	}
+	info.CSDVersion = stringValue(values, "CSDVersion")
+	info.ProductID = stringValue(values, "ProductId")
+	info.RegisteredOwner = stringValue(values, "RegisteredOwner")
+	info.RegisteredOrganization = stringValue(values, "RegisteredOrganization")
+	info.SystemRoot = stringValue(values, "SystemRoot")
+
+	// InstallTime is a FILETIME since Windows 10, InstallDate seconds since
+	// the Unix epoch
+	if installTime := uintValue(values, "InstallTime"); installTime != 0 {
+		info.InstallDate = regffs.FiletimeToTime(installTime)
+	} else if installDate := uintValue(values, "InstallDate"); installDate != 0 {
+		info.InstallDate = time.Unix(int64(installDate), 0).UTC()
+	}
+	return nil
+}
+
+func stringValue(values map[string]*regffs.Value, name string) string {
+	if v, ok := values[strings.ToLower(name)]; ok {
+		return v.String()
+	}
+	return ""
+}
+
+func stringsValue(values map[string]*regffs.Value, name string) []string {
+	v, ok := values[strings.ToLower(name)]
+	if !ok {
+		return nil
+	}
+	if v.Type == regffs.DataTypeEnum.RegMultiSz {
+		strs, _ := v.Strings()
+		return strs
+	}
+	if s := v.String(); s != "" {
+		return []string{s}
+	}
+	return nil
+}
+
+func uintValue(values map[string]*regffs.Value, name string) uint64 {
+	if v, ok := values[strings.ToLower(name)]; ok {
+		u, _ := v.Uint()
+		return u
+	}
+	return 0
+}
@@ -0,0 +1,112 @@
+package sysinfo
+
+import (
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/forensicanalysis/regffs"
+)
+
+func TestParseInterface(t *testing.T) {
+	tests := []struct {
+		name   string
+		values map[string]*regffs.Value
+		want   *Interface
+	}{
+		{
+			"DHCP",
+			map[string]*regffs.Value{
+				"enabledhcp":         regffs.DwordValue(1),
+				"dhcpipaddress":      regffs.StringValue("192.168.1.23"),
+				"dhcpsubnetmask":     regffs.StringValue("255.255.255.0"),
+				"dhcpdefaultgateway": regffs.MultiStringValue("192.168.1.1"),
+				"dhcpnameserver":     regffs.StringValue("192.168.1.1 8.8.8.8"),
+				"dhcpserver":         regffs.StringValue("192.168.1.1"),
+				"leaseobtainedtime":  regffs.DwordValue(1412045974),
+			},
+			&Interface{
+				GUID:           "{guid}",
+				DHCP:           true,
+				IPAddresses:    []string{"192.168.1.23"},
+				SubnetMasks:    []string{"255.255.255.0"},
+				DefaultGateway: []string{"192.168.1.1"},
+				NameServers:    []string{"192.168.1.1", "8.8.8.8"},
+				DHCPServer:     "192.168.1.1",
+				LeaseObtained:  time.Date(2014, 9, 30, 2, 59, 34, 0, time.UTC),
+			},
+		},
+		{
+			"static",
+			map[string]*regffs.Value{
+				"enabledhcp":     regffs.DwordValue(0),
+				"ipaddress":      regffs.MultiStringValue("10.0.0.5"),
+				"subnetmask":     regffs.MultiStringValue("255.0.0.0"),
+				"defaultgateway": regffs.MultiStringValue("10.0.0.1"),
+				"nameserver":     regffs.StringValue("10.0.0.2,10.0.0.3"),
+			},
+			&Interface{
+				GUID:           "{guid}",
+				IPAddresses:    []string{"10.0.0.5"},
+				SubnetMasks:    []string{"255.0.0.0"},
+				DefaultGateway: []string{"10.0.0.1"},
+				NameServers:    []string{"10.0.0.2", "10.0.0.3"},
+			},
+		},
+		{
+			"unconfigured",
+			map[string]*regffs.Value{
+				"enabledhcp": regffs.DwordValue(0),
+				"ipaddress":  regffs.MultiStringValue("0.0.0.0"),
+			},
+			nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseInterface("{guid}", tt.values); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseInterface() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTimeZone(t *testing.T) {
+	got := parseTimeZone(map[string]*regffs.Value{
+		"standardname":   regffs.StringValue("W. Europe Standard Time"),
+		"daylightname":   regffs.StringValue("W. Europe Daylight Time"),
+		"bias":           regffs.DwordValue(0xffffffc4),
+		"activetimebias": regffs.DwordValue(0xffffff88),
+	})
+	want := &TimeZone{
+		Name:           "W. Europe Standard Time",
+		StandardName:   "W. Europe Standard Time",
+		DaylightName:   "W. Europe Daylight Time",
+		Bias:           -60,
+		ActiveTimeBias: -120,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseTimeZone() = %+v, want %+v", got, want)
+	}
+}
+
+func TestSummaryOtherHive(t *testing.T) {
+	f, err := os.Open("../testdata/NTUSER.DAT")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	fsys, err := regffs.New(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := Summary(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(info, &Info{}) {
+		t.Errorf("Summary() = %+v, want empty info", info)
+	}
+}
@@ -0,0 +1,83 @@
+package regffs
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"strings"
+	"time"
+)
+
+// TimelineEntry is a key with its last written time.
+type TimelineEntry struct {
+	Path    string
+	ModTime time.Time
+	Deleted bool
+}
+
+// Bodyfile formats the entry as a line in the Sleuth Kit bodyfile 3.x format
+// (MD5|name|inode|mode|UID|GID|size|atime|mtime|ctime|crtime), as consumed
+// by mactime. Only the mtime is set, to the last written time of the key.
+func (e *TimelineEntry) Bodyfile(prefix string) string {
+	name := strings.ReplaceAll(strings.TrimSuffix(prefix+"/"+e.Path, "/"), "|", "_")
+	if name == "" {
+		name = "/"
+	}
+	if e.Deleted {
+		name += " (deleted)"
+	}
+	var mtime int64
+	if !e.ModTime.IsZero() {
+		mtime = e.ModTime.Unix()
+	}
+	return fmt.Sprintf("0|%s|0|d/d---------|0|0|0|0|%d|0|0", name, mtime)
+}
+
+// Timeline walks all keys of fsys and returns them with their last
+// written times.
+func Timeline(fsys fs.FS) ([]*TimelineEntry, error) {
+	var entries []*TimelineEntry
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if name == "." {
+			name = ""
+		}
+		entries = append(entries, &TimelineEntry{Path: name, ModTime: info.ModTime()})
+		return nil
+	})
+	return entries, err
+}
+
+// DeletedTimeline returns the deleted keys of r with their last written
+// times.
+func (r *Regffs) DeletedTimeline() ([]*TimelineEntry, error) {
+	keys, err := r.DeletedKeys()
+	if err != nil {
+		return nil, err
+	}
+	var entries []*TimelineEntry
+	for _, key := range keys {
+		entries = append(entries, &TimelineEntry{Path: key.Path, ModTime: key.ModTime, Deleted: true})
+	}
+	return entries, nil
+}
+
+// WriteBodyfile writes the entries as bodyfile lines to w. The prefix,
+// usually the hive name, is prepended to all paths.
+func WriteBodyfile(w io.Writer, prefix string, entries []*TimelineEntry) error {
+	for _, entry := range entries {
+		if _, err := fmt.Fprintln(w, entry.Bodyfile(prefix)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
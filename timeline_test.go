@@ -0,0 +1,51 @@
+package regffs
+
+import (
+	"os"
+	"testing"
+)
+
+func TestTimeline(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		deleted  bool
+		expected string
+	}{
+		{"Timeline NTUSER.DAT", "testdata/NTUSER.DAT", false, "0|NTUSER.DAT/AppEvents|0|d/d---------|0|0|0|0|1249398743|0|0"},
+		{"Timeline SAM", "testdata/SAM", false, "0|SAM/SAM/Domains/Account/Users/000001F4|0|d/d---------|0|0|0|0|1411540370|0|0"},
+		{"Deleted SAM", "testdata/SAM", true, "0|SAM/SAM/Domains/Builtin/Aliases/Names/Power Users (deleted)|0|d/d---------|0|0|0|0|1411540196|0|0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := os.Open(tt.file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			fsys, err := New(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var entries []*TimelineEntry
+			if tt.deleted {
+				entries, err = fsys.DeletedTimeline()
+			} else {
+				entries, err = Timeline(fsys)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			prefix := tt.file[len("testdata/"):]
+			for _, entry := range entries {
+				if entry.Bodyfile(prefix) == tt.expected {
+					return
+				}
+			}
+			t.Errorf("line %q not found in %d entries", tt.expected, len(entries))
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package regffs
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Warning is a problem a tolerant Regffs skipped over.
+type Warning struct {
+	// Offset in the hive file, including the base block.
+	Offset  int64
+	Message string
+}
+
+func (w Warning) String() string {
+	return fmt.Sprintf("0x%x: %s", w.Offset, w.Message)
+}
+
+// warnings collects the warnings of a tolerant Regffs. A nil *warnings
+// discards them.
+type warnings struct {
+	list []Warning
+	seen map[Warning]bool
+}
+
+func (w *warnings) add(offset int64, format string, args ...interface{}) {
+	if w == nil {
+		return
+	}
+	warning := Warning{Offset: offset, Message: fmt.Sprintf(format, args...)}
+	if w.seen[warning] {
+		return
+	}
+	w.seen[warning] = true
+	w.list = append(w.list, warning)
+}
+
+// NewTolerant opens a partially corrupted or truncated hive. Unlike New, it
+// accepts a base block that can not be decoded or points to an invalid root
+// key and then uses the first allocated key cell with the KeyHiveEntry flag
+// as root key. Hive bins with invalid headers and cells that can not be
+// decoded are skipped, the problems are recorded and returned by Warnings.
+func NewTolerant(f io.ReadSeeker) (*Regffs, error) {
+	r := &Regffs{reader: f, regf: &Regf{}, warnings: &warnings{seen: map[Warning]bool{}}}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	header := &FileHeader{}
+	if err := header.Decode(f, r.regf, r.regf); err != nil {
+		r.warnings.add(0, "base block: %v", err)
+	} else {
+		if !bytes.Equal(header.Signature(), []byte("regf")) {
+			r.warnings.add(0, "base block: invalid signature %q", header.Signature())
+		}
+		r.header = header
+		r.rootOffset = header.RootKeyOffset()
+		if r.isRootKey(int64(r.rootOffset)) {
+			return r, nil
+		}
+		r.warnings.add(headerRootKeyOffset, "base block: invalid root key offset 0x%x", r.rootOffset)
+	}
+
+	root, err := r.findRootKey()
+	if err != nil {
+		return nil, err
+	}
+	r.rootOffset = root
+	return r, nil
+}
+
+// FindRootKey scans the hive bins of f for the first allocated key cell
+// with the KeyHiveEntry flag, like NewTolerant. Invalid hive bins are
+// skipped and the base block is ignored. The offset is relative to the
+// start of the hive bins data.
+func FindRootKey(f io.ReadSeeker) (uint32, error) {
+	r := &Regffs{reader: f, regf: &Regf{}, warnings: &warnings{seen: map[Warning]bool{}}}
+	return r.findRootKey()
+}
+
+func (r *Regffs) findRootKey() (uint32, error) {
+	var root uint32
+	err := r.walkCells(func(offset int64, cell *HiveBinCell) error {
+		nk, ok := cell.Data().(*NamedKey)
+		if cell.IsAllocated() && ok && nk.Flags()&NkFlags.KeyHiveEntry != 0 {
+			root = uint32(offset)
+			return errStop
+		}
+		return nil
+	})
+	if errors.Is(err, errStop) {
+		return root, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return 0, errors.New("no root key found")
+}
+
+// isRootKey reports whether an allocated key cell with the KeyHiveEntry flag
+// is at offset, relative to the start of the hive bins data.
+func (r *Regffs) isRootKey(offset int64) bool {
+	cell, err := getCell(offset+hiveBinsOffset, r.reader, r.regf)
+	if err != nil || !cell.IsAllocated() {
+		return false
+	}
+	nk, ok := cell.Data().(*NamedKey)
+	return ok && nk.Flags()&NkFlags.KeyHiveEntry != 0
+}
+
+// Warnings returns the problems a Regffs opened by NewTolerant skipped so
+// far. Keys and values are decoded lazily, so walking the hive can add
+// warnings.
+func (r *Regffs) Warnings() []Warning {
+	if r.warnings == nil {
+		return nil
+	}
+	return append([]Warning(nil), r.warnings.list...)
+}
+
+// tolerantBin handles an invalid hive bin header at binOffset while walking
+// the cells. It returns false if the walk stops.
+func (r *Regffs) tolerantBin(binOffset int64, header *HiveBinHeader) bool {
+	if r.warnings == nil {
+		return false
+	}
+	r.warnings.add(binOffset, "hive bin: invalid header %q, size 0x%x", bytes.TrimRight(header.Signature(), "\x00"), header.Size())
+	return true
+}
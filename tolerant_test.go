@@ -0,0 +1,123 @@
+package regffs
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/fs"
+	"os"
+	"testing"
+)
+
+func TestNewTolerant(t *testing.T) {
+	ntuser, err := os.ReadFile("testdata/NTUSER.DAT")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, err := OpenHive(bytes.NewReader(ntuser))
+	if err != nil {
+		t.Fatal(err)
+	}
+	appEvents, err := h.lookup("", "AppEvents")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		corrupt  func(b []byte) []byte
+		missing  string
+		warnings bool
+	}{
+		{"valid", func(b []byte) []byte { return b }, "", false},
+		{"signature", func(b []byte) []byte { copy(b, "xxxx"); return b }, "", true},
+		{"root key offset", func(b []byte) []byte {
+			binary.LittleEndian.PutUint32(b[headerRootKeyOffset:], 0x7fff0000)
+			return b
+		}, "", true},
+		{"base block", func(b []byte) []byte {
+			return append(make([]byte, hiveBinsOffset), b[hiveBinsOffset:]...)
+		}, "", true},
+		{"key cell", func(b []byte) []byte {
+			copy(b[hiveBinsOffset+appEvents+4:], "xx")
+			return b
+		}, "AppEvents", true},
+		{"ri cycle", func(b []byte) []byte {
+			// an ri list of itself and the original subkey list
+			h, err := OpenHive(bytes.NewReader(b))
+			if err != nil {
+				t.Fatal(err)
+			}
+			ri, err := h.alloc(12)
+			if err != nil {
+				t.Fatal(err)
+			}
+			c := h.cell(ri)
+			copy(c, "ri")
+			binary.LittleEndian.PutUint16(c[2:], 2)
+			binary.LittleEndian.PutUint32(c[4:], ri)
+			binary.LittleEndian.PutUint32(c[8:], h.get32(h.rootKey(), nkSubkeyList))
+			h.put32(h.rootKey(), nkSubkeyList, ri)
+			return h.Bytes()
+		}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := tt.corrupt(append([]byte(nil), ntuser...))
+			fsys, err := NewTolerant(bytes.NewReader(b))
+			if err != nil {
+				t.Fatal(err)
+			}
+			entries, err := fs.ReadDir(fsys, ".")
+			if err != nil {
+				t.Fatal(err)
+			}
+			names := map[string]bool{}
+			for _, entry := range entries {
+				names[entry.Name()] = true
+			}
+			for _, name := range []string{"AppEvents", "Control Panel", "Software"} {
+				if names[name] == (name == tt.missing) {
+					t.Errorf("ReadDir() contains %s = %v", name, names[name])
+				}
+			}
+			if got := len(fsys.Warnings()) > 0; got != tt.warnings {
+				t.Errorf("Warnings() = %v", fsys.Warnings())
+			}
+		})
+	}
+}
+
+func TestNewTolerantTruncated(t *testing.T) {
+	ntuser, err := os.ReadFile("testdata/NTUSER.DAT")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := New(bytes.NewReader(ntuser[:0x800])); err == nil {
+		t.Fatal("New() of a truncated base block succeeded")
+	}
+
+	fsys, err := NewTolerant(bytes.NewReader(ntuser[:0x40000]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := 0
+	err = fs.WalkDir(fsys, ".", func(_ string, d fs.DirEntry, err error) error {
+		if err == nil && d.IsDir() {
+			keys++
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys < 100 {
+		t.Errorf("WalkDir() found %d keys", keys)
+	}
+	if len(fsys.Warnings()) == 0 {
+		t.Error("Warnings() is empty")
+	}
+
+	if _, err := NewTolerant(bytes.NewReader(ntuser[:0x800])); err == nil {
+		t.Error("NewTolerant() without hive bins succeeded")
+	}
+}
@@ -0,0 +1,242 @@
+package regffs
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"strconv"
+	"strings"
+)
+
+var dataTypeNames = map[uint32]string{
+	DataTypeEnum.RegNone:                     "REG_NONE",
+	DataTypeEnum.RegSz:                       "REG_SZ",
+	DataTypeEnum.RegExpandSz:                 "REG_EXPAND_SZ",
+	DataTypeEnum.RegBinary:                   "REG_BINARY",
+	DataTypeEnum.RegDword:                    "REG_DWORD",
+	DataTypeEnum.RegDwordBigEndian:           "REG_DWORD_BIG_ENDIAN",
+	DataTypeEnum.RegLink:                     "REG_LINK",
+	DataTypeEnum.RegMultiSz:                  "REG_MULTI_SZ",
+	DataTypeEnum.RegResourceList:             "REG_RESOURCE_LIST",
+	DataTypeEnum.RegFullResourceDescriptor:   "REG_FULL_RESOURCE_DESCRIPTOR",
+	DataTypeEnum.RegResourceRequirementsList: "REG_RESOURCE_REQUIREMENTS_LIST",
+	DataTypeEnum.RegQword:                    "REG_QWORD",
+}
+
+// DataTypeName returns the name of a value data type, e.g. REG_SZ.
+func DataTypeName(dataType uint32) string {
+	if name, ok := dataTypeNames[dataType]; ok {
+		return name
+	}
+	return fmt.Sprintf("0x%x", dataType)
+}
+
+// Value is a registry value with its data type.
+type Value struct {
+	Name string
+	Type uint32
+	Data []byte
+}
+
+// Value reads the value at name.
+func (r *Regffs) Value(name string) (*Value, error) {
+	f, err := r.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return f.(*File).Value()
+}
+
+// Values reads the values of the key at name, mapped by their lower case
+// names. A missing key has no values.
+func (r *Regffs) Values(name string) (map[string]*Value, error) {
+	entries, err := fs.ReadDir(r, name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	values := map[string]*Value{}
+	for _, entry := range entries {
+		f, ok := entry.(*File)
+		if !ok || f.IsDir() {
+			continue
+		}
+		v, err := f.Value()
+		if err != nil {
+			return nil, err
+		}
+		values[strings.ToLower(f.Name())] = v
+	}
+	return values, nil
+}
+
+// Exists reports whether a key or value exists at name.
+func (r *Regffs) Exists(name string) bool {
+	_, err := fs.Stat(r, name)
+	return err == nil
+}
+
+// Value reads the value data of f.
+func (f *File) Value() (*Value, error) {
+	vk, ok := f.cell.Data().(*SubKeyListVk)
+	if !ok {
+		return nil, &fs.PathError{Op: "read", Path: f.Name(), Err: fmt.Errorf("is a key")}
+	}
+	if f.data == nil {
+		if err := f.loadData(vk); err != nil {
+			return nil, err
+		}
+	}
+	data := make([]byte, f.data.Size())
+	if _, err := f.data.ReadAt(data, 0); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return &Value{Name: f.Name(), Type: vk.DataType(), Data: data}, nil
+}
+
+// readValue reads a value from any fs.FS. The data type is taken from the
+// file info if it is a regffs value or if Sys() returns a data type like
+// DataTypeEnum.RegSz, otherwise REG_BINARY is assumed.
+// Regffs files are read directly, as a subkey might share the name.
+func readValue(fsys fs.FS, name string, d fs.DirEntry) (*Value, error) {
+	if f, ok := d.(*File); ok {
+		return f.Value()
+	}
+	info, err := fs.Stat(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: fmt.Errorf("is a key")}
+	}
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	value := &Value{Name: info.Name(), Type: DataTypeEnum.RegBinary, Data: data}
+	switch sys := info.Sys().(type) {
+	case *SubKeyListVk:
+		value.Type = sys.DataType()
+	case uint32:
+		value.Type = sys
+	}
+	return value, nil
+}
+
+// TypeName returns the name of the data type of the value.
+func (v *Value) TypeName() string {
+	return DataTypeName(v.Type)
+}
+
+// Interface returns the decoded data: a string for REG_SZ, REG_EXPAND_SZ
+// and REG_LINK, a []string for REG_MULTI_SZ, a uint32 for the REG_DWORD
+// types, a uint64 for REG_QWORD and the raw []byte for all other types.
+func (v *Value) Interface() (interface{}, error) {
+	switch v.Type {
+	case DataTypeEnum.RegSz, DataTypeEnum.RegExpandSz, DataTypeEnum.RegLink:
+		return decodeString(v.Data)
+	case DataTypeEnum.RegMultiSz:
+		return v.Strings()
+	case DataTypeEnum.RegDword, DataTypeEnum.RegDwordBigEndian:
+		i, err := v.Uint()
+		return uint32(i), err
+	case DataTypeEnum.RegQword:
+		return v.Uint()
+	}
+	return v.Data, nil
+}
+
+// Uint decodes REG_DWORD, REG_DWORD_BIG_ENDIAN and REG_QWORD values.
+func (v *Value) Uint() (uint64, error) {
+	switch {
+	case v.Type == DataTypeEnum.RegDword && len(v.Data) >= 4:
+		return uint64(binary.LittleEndian.Uint32(v.Data)), nil
+	case v.Type == DataTypeEnum.RegDwordBigEndian && len(v.Data) >= 4:
+		return uint64(binary.BigEndian.Uint32(v.Data)), nil
+	case v.Type == DataTypeEnum.RegQword && len(v.Data) >= 8:
+		return binary.LittleEndian.Uint64(v.Data), nil
+	}
+	return 0, fmt.Errorf("%s value with %d bytes is not an integer", v.TypeName(), len(v.Data))
+}
+
+// Strings decodes the value data as list of UTF-16 strings, as used by
+// REG_MULTI_SZ.
+func (v *Value) Strings() ([]string, error) {
+	s, err := DecodeUTF16(evenLength(v.Data))
+	if err != nil {
+		return nil, err
+	}
+	var strs []string
+	for _, str := range strings.Split(strings.TrimRight(s, "\x00"), "\x00") {
+		if str != "" {
+			strs = append(strs, str)
+		}
+	}
+	return strs, nil
+}
+
+// String returns a human readable representation of the value data.
+func (v *Value) String() string {
+	i, err := v.Interface()
+	if err != nil {
+		return hex.EncodeToString(v.Data)
+	}
+	switch i := i.(type) {
+	case string:
+		return i
+	case []string:
+		return strings.Join(i, "\n")
+	case uint32:
+		return strconv.FormatUint(uint64(i), 10)
+	case uint64:
+		return strconv.FormatUint(i, 10)
+	}
+	return hex.EncodeToString(v.Data)
+}
+
+// decodeString decodes an UTF-16 string up to the first end-of-string
+// character.
+func decodeString(b []byte) (string, error) {
+	s, err := DecodeUTF16(evenLength(b))
+	if err != nil {
+		return "", err
+	}
+	if i := strings.IndexByte(s, 0); i >= 0 {
+		s = s[:i]
+	}
+	return s, nil
+}
+
+func evenLength(b []byte) []byte {
+	return b[:len(b)&^1]
+}
+
+// StringValue returns a REG_SZ value of s.
+func StringValue(s string) *Value {
+	return &Value{Type: DataTypeEnum.RegSz, Data: append(EncodeUTF16(s), 0, 0)}
+}
+
+// MultiStringValue returns a REG_MULTI_SZ value of strs.
+func MultiStringValue(strs ...string) *Value {
+	var data []byte
+	for _, s := range strs {
+		data = append(data, EncodeUTF16(s)...)
+		data = append(data, 0, 0)
+	}
+	return &Value{Type: DataTypeEnum.RegMultiSz, Data: append(data, 0, 0)}
+}
+
+// DwordValue returns a REG_DWORD value of i.
+func DwordValue(i uint32) *Value {
+	return &Value{Type: DataTypeEnum.RegDword, Data: binary.LittleEndian.AppendUint32(nil, i)}
+}
+
+// QwordValue returns a REG_QWORD value of i.
+func QwordValue(i uint64) *Value {
+	return &Value{Type: DataTypeEnum.RegQword, Data: binary.LittleEndian.AppendUint64(nil, i)}
+}
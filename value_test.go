@@ -0,0 +1,54 @@
+package regffs
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestValues(t *testing.T) {
+	h, err := NewHive()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := h.CreateKey("Software/Test/Sub"); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]*Value{
+		"string": StringValue("abc"),
+		"multi":  MultiStringValue("a", "b"),
+		"dword":  DwordValue(7),
+		"qword":  QwordValue(1 << 40),
+	}
+	names := map[string]string{"string": "String", "multi": "Multi", "dword": "DWORD", "qword": "qword"}
+	for key, v := range want {
+		v.Name = names[key]
+		if err := h.SetValue("Software/Test", v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r, err := h.Regffs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := r.Values("Software/Test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(want) {
+		t.Errorf("Values() = %d values, want %d", len(got), len(want))
+	}
+	for key, v := range want {
+		if g, ok := got[key]; !ok || g.Name != v.Name || g.Type != v.Type || !bytes.Equal(g.Data, v.Data) {
+			t.Errorf("Values()[%s] = %+v, want %+v", key, g, v)
+		}
+	}
+	if v, ok := got["multi"]; ok && v.String() != "a\nb" {
+		t.Errorf("multi = %q, want %q", v.String(), "a\nb")
+	}
+
+	missing, err := r.Values("Software/Missing")
+	if err != nil || missing != nil {
+		t.Errorf("Values(Software/Missing) = %v, %v, want none", missing, err)
+	}
+}